@@ -1,6 +1,9 @@
 package signer
 
-import "github.com/viant/scy"
+import (
+	"crypto"
+	"github.com/viant/scy"
+)
 
 type Rule struct {
 	Resource  []string      `json:",omitempty" yaml:"Resource,omitempty"`
@@ -8,11 +11,15 @@ type Rule struct {
 	Compact   bool          `json:",omitempty" yaml:"Compact,omitempty"`
 	RSA       *scy.Resource `json:",omitempty" yaml:"RSA,omitempty"`
 	HMAC      *scy.Resource `json:",omitempty" yaml:"HMAC,omitempty"`
+	KMS       string        `json:",omitempty" yaml:"KMS,omitempty"` //asymmetric kms key version URL, private key never leaves kms
+	Signer    crypto.Signer `json:"-" yaml:"-"`
 }
 
 type Config struct {
 	RSA     *scy.Resource `json:",omitempty" yaml:"RSA,omitempty"`
 	HMAC    *scy.Resource `json:",omitempty" yaml:"HMAC,omitempty"`
+	KMS     string        `json:",omitempty" yaml:"KMS,omitempty"` //asymmetric kms key version URL, private key never leaves kms
+	Signer  crypto.Signer `json:"-" yaml:"-"`
 	Compact bool          `json:",omitempty" yaml:"Compact,omitempty"`
 	Rules   []*Rule       `json:",omitempty" yaml:"Rules,omitempty"`
 }
@@ -0,0 +1,63 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"strings"
+)
+
+// signerMethod signs with crypto.Signer (i.e. kms backed key), tokens verify with the standard RSA or RSA-PSS method
+type signerMethod struct {
+	jwt.SigningMethod
+	hash crypto.Hash
+	opts crypto.SignerOpts
+}
+
+// Sign hashes signing string and signs the digest with crypto.Signer key
+func (m *signerMethod) Sign(signingString string, key interface{}) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, jwt.ErrInvalidKeyType
+	}
+	if !m.hash.Available() {
+		return nil, jwt.ErrHashUnavailable
+	}
+	hasher := m.hash.New()
+	hasher.Write([]byte(signingString))
+	return signer.Sign(rand.Reader, hasher.Sum(nil), m.opts)
+}
+
+// newSignerMethod wraps RSA (RS*) or RSA-PSS (PS*) method to sign with crypto.Signer
+func newSignerMethod(method jwt.SigningMethod) (*signerMethod, error) {
+	switch actual := method.(type) {
+	case *jwt.SigningMethodRSAPSS:
+		return &signerMethod{SigningMethod: actual, hash: actual.Hash, opts: &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: actual.Hash}}, nil
+	case *jwt.SigningMethodRSA:
+		return &signerMethod{SigningMethod: actual, hash: actual.Hash, opts: actual.Hash}, nil
+	}
+	return nil, fmt.Errorf("create: algorithm %v requires RSA key", method.Alg())
+}
+
+// kmsAlgorithm returns JWT algorithm for KMS key algorithm, i.e. RSA_SIGN_PSS_2048_SHA256 -> PS256,
+// RSA_SIGN_PKCS1_4096_SHA512 -> RS512
+func kmsAlgorithm(algorithm string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(algorithm))
+	var prefix string
+	switch {
+	case strings.HasPrefix(normalized, "RSA_SIGN_PSS_"):
+		prefix = "PS"
+	case strings.HasPrefix(normalized, "RSA_SIGN_PKCS1_"):
+		prefix = "RS"
+	default:
+		return "", fmt.Errorf("create: unsupported kms key algorithm %q", algorithm)
+	}
+	for _, size := range []string{"256", "384", "512"} {
+		if strings.HasSuffix(normalized, "_SHA"+size) {
+			return prefix + size, nil
+		}
+	}
+	return "", fmt.Errorf("create: unsupported kms key algorithm %q", algorithm)
+}
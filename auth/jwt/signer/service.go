@@ -2,6 +2,7 @@ package signer
 
 import (
 	"context"
+	"crypto"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
@@ -10,6 +11,7 @@ import (
 	"github.com/viant/scy"
 	jwt2 "github.com/viant/scy/auth/jwt"
 	sjwt "github.com/viant/scy/auth/jwt"
+	"github.com/viant/scy/kms"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +27,7 @@ type profile struct {
 	privateKey *rsa.PrivateKey
 	kid        string
 	hmac       []byte
+	signer     crypto.Signer
 	sync.RWMutex
 }
 
@@ -79,6 +82,9 @@ func (s *Service) Create(ttl time.Duration, content interface{}, options ...Toke
 
 func (p *profile) signingMethod() (jwt.SigningMethod, error) {
 	algorithm := strings.ToUpper(strings.TrimSpace(p.algorithm))
+	if p.signer != nil {
+		return p.signerMethod(algorithm)
+	}
 	if algorithm == "" {
 		if len(p.hmac) > 0 {
 			return jwt.SigningMethodHS512, nil
@@ -101,10 +107,36 @@ func (p *profile) signingMethod() (jwt.SigningMethod, error) {
 	return method, nil
 }
 
+// signerMethod returns crypto.Signer method, KMS signer key algorithm (i.e. RSA_SIGN_PSS_2048_SHA256) determines the method,
+// configured algorithm has to match it, RS256 is used for other RSA signers by default
+func (p *profile) signerMethod(algorithm string) (jwt.SigningMethod, error) {
+	if kmsSigner, ok := p.signer.(interface{ Algorithm() string }); ok {
+		keyAlgorithm, err := kmsAlgorithm(kmsSigner.Algorithm())
+		if err != nil {
+			return nil, err
+		}
+		if algorithm != "" && algorithm != keyAlgorithm {
+			return nil, fmt.Errorf("create: algorithm %q does not match kms key algorithm %q", p.algorithm, kmsSigner.Algorithm())
+		}
+		algorithm = keyAlgorithm
+	}
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodRS256.Alg()
+	}
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("create: unsupported signing algorithm %q", p.algorithm)
+	}
+	return newSignerMethod(method)
+}
+
 func (p *profile) signingKey() (interface{}, string, error) {
 	if len(p.hmac) > 0 {
 		return p.hmac, "", nil
 	}
+	if p.signer != nil {
+		return p.signer, p.kid, nil
+	}
 	privateKey, err := p.getPrivateKey()
 	if err != nil {
 		return nil, "", err
@@ -137,12 +169,27 @@ func (p *profile) getPrivateKey() (*rsa.PrivateKey, error) {
 	return privateKey, nil
 }
 
-func (p *profile) init(ctx context.Context, rsaResource, hmacResource *scy.Resource) error {
-	if rsaResource != nil && hmacResource != nil {
-		return fmt.Errorf("create: both RSA and HMAC were configured for one signing profile")
+func (p *profile) init(ctx context.Context, rsaResource, hmacResource *scy.Resource, kmsKey string, signer crypto.Signer) error {
+	configured := 0
+	for _, candidate := range []bool{rsaResource != nil, hmacResource != nil, kmsKey != "", signer != nil} {
+		if candidate {
+			configured++
+		}
+	}
+	if configured > 1 {
+		return fmt.Errorf("create: more than one of RSA, HMAC, KMS or Signer was configured for one signing profile")
+	}
+	if configured == 0 {
+		return fmt.Errorf("create: signing profile was missing RSA, HMAC, KMS or Signer key")
+	}
+	if kmsKey != "" {
+		var err error
+		if signer, err = kms.NewSigner(ctx, kmsKey); err != nil {
+			return fmt.Errorf("create: failed to create kms signer: %w", err)
+		}
 	}
-	if rsaResource == nil && hmacResource == nil {
-		return fmt.Errorf("create: signing profile was missing RSA or HMAC key")
+	if signer != nil {
+		return p.initSigner(signer)
 	}
 	if rsaResource != nil {
 		scySrv := scy.New()
@@ -165,6 +212,21 @@ func (p *profile) init(ctx context.Context, rsaResource, hmacResource *scy.Resou
 	return nil
 }
 
+func (p *profile) initSigner(signer crypto.Signer) error {
+	publicKey, ok := signer.Public().(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("create: unsupported signer public key type: %T", signer.Public())
+	}
+	kid, err := sjwt.GenerateKid(publicKey)
+	if err != nil {
+		return fmt.Errorf("create: failed to create kid: %w", err)
+	}
+	p.signer = signer
+	p.kid = kid
+	_, err = p.signingMethod()
+	return err
+}
+
 func (p *profile) matches(audience []string) bool {
 	if len(p.resources) == 0 {
 		return true
@@ -195,9 +257,9 @@ func (s *Service) Init(ctx context.Context) error {
 	if s.config == nil {
 		return nil
 	}
-	if s.config.RSA != nil || s.config.HMAC != nil {
+	if s.config.RSA != nil || s.config.HMAC != nil || s.config.KMS != "" || s.config.Signer != nil {
 		s.defaultProfile = &profile{compact: s.config.Compact}
-		if err := s.defaultProfile.init(ctx, s.config.RSA, s.config.HMAC); err != nil {
+		if err := s.defaultProfile.init(ctx, s.config.RSA, s.config.HMAC, s.config.KMS, s.config.Signer); err != nil {
 			return err
 		}
 	}
@@ -210,7 +272,7 @@ func (s *Service) Init(ctx context.Context) error {
 			algorithm: rule.Algorithm,
 			compact:   rule.Compact,
 		}
-		if err := candidate.init(ctx, rule.RSA, rule.HMAC); err != nil {
+		if err := candidate.init(ctx, rule.RSA, rule.HMAC, rule.KMS, rule.Signer); err != nil {
 			return err
 		}
 		s.rules = append(s.rules, candidate)
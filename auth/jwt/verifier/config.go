@@ -7,11 +7,13 @@ type Rule struct {
 	Algorithm string          `json:",omitempty" yaml:"Algorithm,omitempty"`
 	RSA       []*scy.Resource `json:",omitempty" yaml:"RSA,omitempty"`
 	HMAC      *scy.Resource   `json:",omitempty" yaml:"HMAC,omitempty"`
+	KMS       []string        `json:",omitempty" yaml:"KMS,omitempty"` //asymmetric kms key version URLs, public keys are fetched from kms
 }
 
 type Config struct {
 	RSA     []*scy.Resource `json:",omitempty" yaml:"RSA,omitempty"`
 	HMAC    *scy.Resource   `json:",omitempty" yaml:"HMAC,omitempty"`
+	KMS     []string        `json:",omitempty" yaml:"KMS,omitempty"` //asymmetric kms key version URLs, public keys are fetched from kms
 	CertURL string          `json:",omitempty" yaml:"CertURL,omitempty"`
 	Rules   []*Rule         `json:",omitempty" yaml:"Rules,omitempty"`
 }
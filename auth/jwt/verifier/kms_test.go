@@ -0,0 +1,182 @@
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sjwt "github.com/viant/scy/auth/jwt"
+	"github.com/viant/scy/auth/jwt/signer"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/gcp"
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// newFakeKMS returns cloud kms endpoint serving publicKey and asymmetricSign for the supplied private key and key algorithm
+func newFakeKMS(privateKey *rsa.PrivateKey, algorithm string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/publicKey"):
+			der, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+			response = &cloudkms.PublicKey{Algorithm: algorithm, Pem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":asymmetricSign"):
+			request := &cloudkms.AsymmetricSignRequest{}
+			if err := json.NewDecoder(r.Body).Decode(request); err != nil || request.Digest == nil {
+				http.Error(w, "invalid request", http.StatusBadRequest)
+				return
+			}
+			hash, encoded := crypto.SHA256, request.Digest.Sha256
+			switch {
+			case request.Digest.Sha384 != "":
+				hash, encoded = crypto.SHA384, request.Digest.Sha384
+			case request.Digest.Sha512 != "":
+				hash, encoded = crypto.SHA512, request.Digest.Sha512
+			}
+			digest, _ := base64.StdEncoding.DecodeString(encoded)
+			var signature []byte
+			var err error
+			if strings.HasPrefix(algorithm, "RSA_SIGN_PSS_") {
+				signature, err = rsa.SignPSS(rand.Reader, privateKey, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			} else {
+				signature, err = rsa.SignPKCS1v15(rand.Reader, privateKey, hash, digest)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			response = &cloudkms.AsymmetricSignResponse{Signature: base64.StdEncoding.EncodeToString(signature)}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestService_KMS(t *testing.T) {
+	ctx := context.Background()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err) {
+		return
+	}
+	server := newFakeKMS(privateKey, "RSA_SIGN_PKCS1_2048_SHA256")
+	defer server.Close()
+	cipher, err := gcp.New(ctx, option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	if !assert.Nil(t, err) {
+		return
+	}
+	kms.Register(gcp.Scheme, cipher)
+	keyURL := "gcp://kms/projects/p/locations/global/keyRings/r/cryptoKeys/jwt/cryptoKeyVersions/1"
+
+	kmsSigner := signer.New(&signer.Config{KMS: keyURL})
+	if !assert.Nil(t, kmsSigner.Init(ctx)) {
+		return
+	}
+	tokenString, err := kmsSigner.Create(time.Hour, &sjwt.Claims{UserID: 123})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	srv := New(&Config{KMS: []string{keyURL}})
+	if !assert.Nil(t, srv.Init(ctx)) {
+		return
+	}
+	token, err := srv.Validate(ctx, tokenString)
+	if !assert.Nil(t, err) {
+		return
+	}
+	kid, err := sjwt.GenerateKid(&privateKey.PublicKey)
+	assert.Nil(t, err)
+	assert.EqualValues(t, kid, token.Header["kid"])
+	assert.EqualValues(t, "RS256", token.Header["alg"])
+	claims, err := sjwt.NewClaim(token)
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, 123, claims.UserID)
+	}
+	keys, err := srv.PublicKeys()
+	assert.Nil(t, err)
+	assert.EqualValues(t, &privateKey.PublicKey, keys[kid])
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err) {
+		return
+	}
+	otherSigner := signer.New(&signer.Config{Signer: other})
+	if !assert.Nil(t, otherSigner.Init(ctx)) {
+		return
+	}
+	tokenString, err = otherSigner.Create(time.Hour, &sjwt.Claims{UserID: 123})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = srv.Validate(ctx, tokenString)
+	assert.NotNil(t, err, "token signed with different key")
+}
+
+func TestService_KMS_Algorithm(t *testing.T) {
+	ctx := context.Background()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.Nil(t, err) {
+		return
+	}
+	keyURL := "gcp://kms/projects/p/locations/global/keyRings/r/cryptoKeys/jwt/cryptoKeyVersions/1"
+	var testCases = []struct {
+		description  string
+		kmsAlgorithm string
+		algorithm    string
+		expect       string
+		expectErr    bool
+	}{
+		{description: "pkcs1 sha256", kmsAlgorithm: "RSA_SIGN_PKCS1_2048_SHA256", expect: "RS256"},
+		{description: "pkcs1 sha512", kmsAlgorithm: "RSA_SIGN_PKCS1_4096_SHA512", expect: "RS512"},
+		{description: "pss sha256", kmsAlgorithm: "RSA_SIGN_PSS_2048_SHA256", expect: "PS256"},
+		{description: "pss sha512", kmsAlgorithm: "RSA_SIGN_PSS_4096_SHA512", expect: "PS512"},
+		{description: "matching algorithm", kmsAlgorithm: "RSA_SIGN_PSS_3072_SHA256", algorithm: "PS256", expect: "PS256"},
+		{description: "mismatched algorithm", kmsAlgorithm: "RSA_SIGN_PSS_2048_SHA256", algorithm: "RS256", expectErr: true},
+		{description: "unsupported algorithm", kmsAlgorithm: "RSA_SIGN_RAW_PKCS1_2048", expectErr: true},
+	}
+	for _, testCase := range testCases {
+		server := newFakeKMS(privateKey, testCase.kmsAlgorithm)
+		cipher, err := gcp.New(ctx, option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+		if !assert.Nil(t, err, testCase.description) {
+			server.Close()
+			continue
+		}
+		kms.Register(gcp.Scheme, cipher)
+		kmsSigner := signer.New(&signer.Config{Rules: []*signer.Rule{{KMS: keyURL, Algorithm: testCase.algorithm}}})
+		err = kmsSigner.Init(ctx)
+		if testCase.expectErr {
+			assert.NotNil(t, err, testCase.description)
+			server.Close()
+			continue
+		}
+		if !assert.Nil(t, err, testCase.description) {
+			server.Close()
+			continue
+		}
+		tokenString, err := kmsSigner.Create(time.Hour, &sjwt.Claims{UserID: 123})
+		if assert.Nil(t, err, testCase.description) {
+			srv := New(&Config{KMS: []string{keyURL}})
+			if assert.Nil(t, srv.Init(ctx), testCase.description) {
+				token, err := srv.Validate(ctx, tokenString)
+				if assert.Nil(t, err, testCase.description) {
+					assert.EqualValues(t, testCase.expect, token.Header["alg"], testCase.description)
+				}
+			}
+		}
+		server.Close()
+	}
+}
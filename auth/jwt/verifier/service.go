@@ -9,6 +9,7 @@ import (
 	"github.com/viant/scy"
 	sjwt "github.com/viant/scy/auth/jwt"
 	"github.com/viant/scy/auth/jwt/cache"
+	"github.com/viant/scy/kms"
 	"strings"
 )
 
@@ -111,8 +112,11 @@ func (p *profile) supportsAlg(alg string) bool {
 		return false
 	}
 	if p.hasRSA() {
-		_, ok := method.(*jwt.SigningMethodRSA)
-		return ok
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+		return false
 	}
 	if p.hasHMAC() {
 		_, ok := method.(*jwt.SigningMethodHMAC)
@@ -158,11 +162,12 @@ func (s *Service) validateWithPublicKey(tokenString string) (*jwt.Token, error)
 	return token, nil
 }
 
-func (p *profile) init(ctx context.Context, rsaResources []*scy.Resource, hmacResource *scy.Resource) error {
-	if len(rsaResources) > 0 && hmacResource != nil {
+func (p *profile) init(ctx context.Context, rsaResources []*scy.Resource, hmacResource *scy.Resource, kmsKeys []string) error {
+	hasRSA := len(rsaResources) > 0 || len(kmsKeys) > 0
+	if hasRSA && hmacResource != nil {
 		return fmt.Errorf("both RSA and HMAC were configured for one verification profile")
 	}
-	if len(rsaResources) == 0 && hmacResource == nil {
+	if !hasRSA && hmacResource == nil {
 		return fmt.Errorf("verification profile was missing RSA or HMAC key")
 	}
 	scySrv := scy.New()
@@ -196,6 +201,31 @@ func (p *profile) init(ctx context.Context, rsaResources []*scy.Resource, hmacRe
 			p.publicKeys[kid] = publicKey
 		}
 	}
+	for _, kmsKey := range kmsKeys {
+		if err := p.addKMSPublicKey(ctx, kmsKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *profile) addKMSPublicKey(ctx context.Context, kmsKey string) error {
+	signer, err := kms.NewSigner(ctx, kmsKey)
+	if err != nil {
+		return fmt.Errorf("failed to get kms public key: %w", err)
+	}
+	publicKey, ok := signer.Public().(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported kms public key type: %T", signer.Public())
+	}
+	kid, err := sjwt.GenerateKid(publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to generate kid: %w", err)
+	}
+	if p.publicKeys == nil {
+		p.publicKeys = make(map[string]*rsa.PublicKey)
+	}
+	p.publicKeys[kid] = publicKey
 	return nil
 }
 
@@ -272,9 +302,9 @@ func (s *Service) Init(ctx context.Context) error {
 	if s.config == nil {
 		return nil
 	}
-	if len(s.config.RSA) > 0 || s.config.HMAC != nil {
+	if len(s.config.RSA) > 0 || s.config.HMAC != nil || len(s.config.KMS) > 0 {
 		s.defaultProfile = &profile{}
-		if err := s.defaultProfile.init(ctx, s.config.RSA, s.config.HMAC); err != nil {
+		if err := s.defaultProfile.init(ctx, s.config.RSA, s.config.HMAC, s.config.KMS); err != nil {
 			return err
 		}
 	}
@@ -286,7 +316,7 @@ func (s *Service) Init(ctx context.Context) error {
 			resources: append([]string{}, rule.Resource...),
 			algorithm: rule.Algorithm,
 		}
-		if err := candidate.init(ctx, rule.RSA, rule.HMAC); err != nil {
+		if err := candidate.init(ctx, rule.RSA, rule.HMAC, rule.KMS); err != nil {
 			return err
 		}
 		s.rules = append(s.rules, candidate)
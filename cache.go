@@ -0,0 +1,223 @@
+package scy
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//DefaultCacheTTL represents default period a cached secret is served without reloading
+	DefaultCacheTTL = 5 * time.Minute
+	//DefaultCacheMaxSize represents default max number of cached secrets
+	DefaultCacheMaxSize = 1024
+	//DefaultCacheLoadTimeout represents default timeout of a secret load shared by concurrent callers
+	DefaultCacheLoadTimeout = time.Minute
+)
+
+// CacheConfig represents Service secret cache config
+type CacheConfig struct {
+	TTL time.Duration //fresh period, DefaultCacheTTL when zero
+	//StaleTTL is a period after TTL when the stale secret is still served while it is reloaded in the background,
+	//a failed background reload keeps serving the stale secret until StaleTTL elapses
+	StaleTTL time.Duration
+	MaxSize  int //max number of cached secrets, least recently used are evicted, DefaultCacheMaxSize when zero
+	//LoadTimeout limits a load shared by concurrent callers, it does not run with the caller context, so that the cancellation
+	//of a caller does not fail the others, DefaultCacheLoadTimeout when zero
+	LoadTimeout time.Duration
+}
+
+// Option represents Service option
+type Option func(s *Service)
+
+// WithCache enables secret cache keyed by resource URL, key and target type, concurrent loads of the same secret are collapsed,
+// cached secrets are shared between callers and must not be modified
+func WithCache(config CacheConfig) Option {
+	return func(s *Service) {
+		s.cache = newSecretCache(config)
+	}
+}
+
+type (
+	cacheEntry struct {
+		key        string
+		URL        string
+		secret     *Secret
+		loaded     time.Time
+		refreshing bool
+		element    *list.Element
+	}
+
+	cacheCall struct {
+		URL    string
+		done   chan struct{}
+		secret *Secret
+		err    error
+		stale  bool //invalidated while loading, the result is not cached
+	}
+
+	secretCache struct {
+		config   CacheConfig
+		mux      sync.Mutex
+		entries  map[string]*cacheEntry
+		lru      *list.List
+		inflight map[string]*cacheCall
+	}
+)
+
+// get returns cached secret, stale secret triggers background reload, missing or expired secret is loaded once for concurrent callers
+func (c *secretCache) get(ctx context.Context, key, URL string, load func(ctx context.Context) (*Secret, error)) (*Secret, error) {
+	c.mux.Lock()
+	if entry, ok := c.entries[key]; ok {
+		age := time.Since(entry.loaded)
+		switch {
+		case age < c.config.TTL:
+			c.lru.MoveToFront(entry.element)
+			c.mux.Unlock()
+			return entry.secret.clone(), nil
+		case age < c.config.TTL+c.config.StaleTTL:
+			c.lru.MoveToFront(entry.element)
+			if !entry.refreshing {
+				entry.refreshing = true
+				go c.refresh(context.WithoutCancel(ctx), key, URL, load)
+			}
+			c.mux.Unlock()
+			return entry.secret.clone(), nil
+		default:
+			c.remove(entry)
+		}
+	}
+	c.mux.Unlock()
+	return c.load(ctx, key, URL, load)
+}
+
+func (c *secretCache) refresh(ctx context.Context, key, URL string, load func(ctx context.Context) (*Secret, error)) {
+	if _, err := c.load(ctx, key, URL, load); err != nil {
+		c.mux.Lock()
+		if entry, ok := c.entries[key]; ok {
+			entry.refreshing = false
+		}
+		c.mux.Unlock()
+	}
+}
+
+// load loads secret once for concurrent callers and caches it, every caller stops waiting when its context is done
+func (c *secretCache) load(ctx context.Context, key, URL string, load func(ctx context.Context) (*Secret, error)) (*Secret, error) {
+	c.mux.Lock()
+	call, ok := c.inflight[key]
+	if !ok {
+		call = &cacheCall{URL: URL, done: make(chan struct{})}
+		c.inflight[key] = call
+		go c.run(context.WithoutCancel(ctx), key, URL, call, load)
+	}
+	c.mux.Unlock()
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	return call.secret.clone(), nil
+}
+
+// run runs shared load with its own timeout and caches the loaded secret
+func (c *secretCache) run(ctx context.Context, key, URL string, call *cacheCall, load func(ctx context.Context) (*Secret, error)) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.LoadTimeout)
+	defer cancel()
+	call.secret, call.err = load(ctx)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.inflight[key] == call {
+		delete(c.inflight, key)
+	}
+	if call.err == nil && !call.stale {
+		c.put(key, URL, call.secret)
+	}
+	close(call.done)
+}
+
+func (c *secretCache) put(key, URL string, secret *Secret) {
+	if entry, ok := c.entries[key]; ok {
+		c.remove(entry)
+	}
+	entry := &cacheEntry{key: key, URL: URL, secret: secret, loaded: time.Now()}
+	entry.element = c.lru.PushFront(entry)
+	c.entries[key] = entry
+	for c.lru.Len() > c.config.MaxSize {
+		c.remove(c.lru.Back().Value.(*cacheEntry))
+	}
+}
+
+func (c *secretCache) remove(entry *cacheEntry) {
+	c.lru.Remove(entry.element)
+	delete(c.entries, entry.key)
+}
+
+// invalidate removes cached secrets for supplied URLs, all secrets without URLs,
+// secrets being loaded (i.e. by background refresh) are not cached once loaded
+func (c *secretCache) invalidate(URLs ...string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	matches := func(candidate string) bool {
+		for _, URL := range URLs {
+			if candidate == URL || candidate == expandHome(URL) {
+				return true
+			}
+		}
+		return len(URLs) == 0
+	}
+	for key, call := range c.inflight {
+		if matches(call.URL) {
+			call.stale = true
+			delete(c.inflight, key)
+		}
+	}
+	if len(URLs) == 0 {
+		c.entries = map[string]*cacheEntry{}
+		c.lru.Init()
+		return
+	}
+	for _, entry := range c.entries {
+		if matches(entry.URL) {
+			c.remove(entry)
+		}
+	}
+}
+
+// cacheKey returns cache key for resource, empty key for resources that are not cached (inline data)
+func (r *Resource) cacheKey() string {
+	if len(r.Data) > 0 || r.URL == "" || strings.HasPrefix(strings.TrimSpace(r.URL), inlineBase64Prefix) {
+		return ""
+	}
+	target := ""
+	if r.target != nil {
+		target = r.target.String()
+	}
+	return strings.Join([]string{expandHome(r.URL), r.Key, target, r.AssociatedData, r.Version}, "\x00")
+}
+
+// clone returns secret copy sharing the target
+func (s *Secret) clone() *Secret {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	return &clone
+}
+
+func newSecretCache(config CacheConfig) *secretCache {
+	if config.TTL <= 0 {
+		config.TTL = DefaultCacheTTL
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultCacheMaxSize
+	}
+	if config.LoadTimeout <= 0 {
+		config.LoadTimeout = DefaultCacheLoadTimeout
+	}
+	return &secretCache{config: config, entries: map[string]*cacheEntry{}, lru: list.New(), inflight: map[string]*cacheCall{}}
+}
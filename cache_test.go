@@ -0,0 +1,142 @@
+package scy_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/kms/memory"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestService_Load_Cache(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("cache")
+	URL := path.Join(t.TempDir(), "cached.sec")
+	srv := scy.New(scy.WithCache(scy.CacheConfig{TTL: 50 * time.Millisecond, StaleTTL: time.Second, MaxSize: 2}))
+	store := func(value string) {
+		assert.Nil(t, scy.New().Store(ctx, scy.NewSecret(value, scy.NewResource("", URL, key))))
+	}
+	load := func() string {
+		secret, err := srv.Load(ctx, scy.NewResource("", URL, key))
+		if !assert.Nil(t, err) {
+			return ""
+		}
+		return secret.String()
+	}
+	store("v1")
+	cipher.Reset()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.EqualValues(t, "v1", load())
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, cipher.Count(memory.Decrypt), "concurrent loads collapsed")
+
+	store("v2")
+	assert.EqualValues(t, "v1", load(), "fresh cached secret")
+	time.Sleep(60 * time.Millisecond)
+	assert.EqualValues(t, "v1", load(), "stale secret is served while reloading")
+	assert.Eventually(t, func() bool { return load() == "v2" }, time.Second, 5*time.Millisecond, "background refresh")
+
+	cipher.Fail(key, fmt.Errorf("kms unavailable"), memory.Decrypt)
+	store("v3")
+	time.Sleep(60 * time.Millisecond)
+	decrypted := cipher.Count(memory.Decrypt)
+	assert.EqualValues(t, "v2", load(), "failed refresh keeps stale secret")
+	assert.Eventually(t, func() bool { return cipher.Count(memory.Decrypt) == decrypted+1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, "v2", load())
+	assert.Eventually(t, func() bool { return cipher.Count(memory.Decrypt) == decrypted+2 }, time.Second, time.Millisecond, "failed refresh is retried")
+	cipher.Fail(key, nil)
+
+	srv.InvalidateCache(URL)
+	assert.EqualValues(t, "v3", load(), "invalidated")
+
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret("v4", scy.NewResource("", URL, key))))
+	assert.EqualValues(t, "v4", load(), "store invalidates cache")
+
+	for i := 0; i < 3; i++ {
+		otherURL := path.Join(t.TempDir(), fmt.Sprintf("other%v.sec", i))
+		assert.Nil(t, os.WriteFile(otherURL, []byte("other"), 0600))
+		_, err := srv.Load(ctx, scy.NewResource("", otherURL, ""))
+		assert.Nil(t, err)
+	}
+	decrypted = cipher.Count(memory.Decrypt)
+	assert.EqualValues(t, "v4", load(), "evicted by max size")
+	assert.EqualValues(t, decrypted+1, cipher.Count(memory.Decrypt))
+}
+
+func TestService_Load_CacheInvalidatedRefresh(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("refresh")
+	URL := path.Join(t.TempDir(), "refreshed.sec")
+	srv := scy.New(scy.WithCache(scy.CacheConfig{TTL: 50 * time.Millisecond, StaleTTL: time.Minute}))
+	load := func() string {
+		secret, err := srv.Load(ctx, scy.NewResource("", URL, key))
+		if !assert.Nil(t, err) {
+			return ""
+		}
+		return secret.String()
+	}
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret("v1", scy.NewResource("", URL, key))))
+	assert.EqualValues(t, "v1", load())
+	time.Sleep(60 * time.Millisecond)
+	cipher.Delay(key, 100*time.Millisecond, memory.Decrypt)
+	assert.EqualValues(t, "v1", load(), "stale secret starts background refresh")
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret("v2", scy.NewResource("", URL, key))))
+	cipher.Delay(key, 0, memory.Decrypt)
+	assert.EqualValues(t, "v2", load(), "store does not wait for refresh")
+	time.Sleep(110 * time.Millisecond) //refresh started before store completes
+	decrypted := cipher.Count(memory.Decrypt)
+	assert.EqualValues(t, "v2", load(), "refresh started before store is not cached")
+	assert.Eventually(t, func() bool { return cipher.Count(memory.Decrypt) == decrypted+1 }, time.Second, time.Millisecond, "stale v2 refresh")
+}
+
+func TestService_Load_CacheCancelledCaller(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("cancel")
+	URL := path.Join(t.TempDir(), "cancelled.sec")
+	assert.Nil(t, scy.New().Store(ctx, scy.NewSecret("v1", scy.NewResource("", URL, key))))
+	srv := scy.New(scy.WithCache(scy.CacheConfig{}))
+	cipher.Delay(key, 100*time.Millisecond, memory.Decrypt)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	started := make(chan error)
+	go func() {
+		_, err := srv.Load(cancelCtx, scy.NewResource("", URL, key))
+		started <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	waiter := make(chan *scy.Secret)
+	go func() {
+		secret, err := srv.Load(ctx, scy.NewResource("", URL, key))
+		assert.Nil(t, err, "waiter does not share cancelled caller error")
+		waiter <- secret
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-started, context.Canceled, "cancelled caller stops waiting")
+	if secret := <-waiter; assert.NotNil(t, secret) {
+		assert.EqualValues(t, "v1", secret.String())
+	}
+	assert.EqualValues(t, 1, cipher.Count(memory.Decrypt), "load shared by both callers")
+
+	cipher.Delay(key, time.Second, memory.Decrypt)
+	srv = scy.New(scy.WithCache(scy.CacheConfig{LoadTimeout: 50 * time.Millisecond}))
+	_, err := srv.Load(ctx, scy.NewResource("", URL, key))
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "shared load timeout")
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/viant/scy"
+	"github.com/viant/scy/auth/jwt/verifier"
+	"github.com/viant/scy/kms/remote"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// KmsdCmd command for serving Encrypt/Decrypt with held keys to JWT authenticated callers
+type KmsdCmd struct {
+	Listen    string   `short:"l" long:"listen" default:"localhost:8443" description:"listen address host:port or unix:///path/to/kmsd.sock"`
+	Keys      []string `short:"k" long:"key" description:"served key name=URL i.e app=aes://env/APP_KEY, repeatable"`
+	Allow     []string `short:"p" long:"allow" description:"key caller subject name=subject i.e app=ci@example.com, * allows any authenticated caller, repeatable"`
+	Audiences []string `long:"audience" description:"key caller audience name=audience i.e app=deploy, repeatable"`
+	RSAKey    string   `short:"r" long:"rsa" description:"JWT verification public key location"`
+	HMacKey   string   `short:"a" long:"hmac" description:"JWT verification hmac key location (base64 encoded)"`
+	AuthKey   string   `short:"e" long:"authKey" description:"JWT verification key secret key i.e blowfish://default"`
+	TLSCert   string   `long:"tlsCert" description:"TLS certificate location"`
+	TLSKey    string   `long:"tlsKey" description:"TLS private key location"`
+}
+
+// Init normalizes file locations
+func (k *KmsdCmd) Init() {
+	k.RSAKey = normalizeLocation(k.RSAKey)
+	k.HMacKey = normalizeLocation(k.HMacKey)
+	k.TLSCert = normalizeLocation(k.TLSCert)
+	k.TLSKey = normalizeLocation(k.TLSKey)
+}
+
+// Validate validates the kmsd command options
+func (k *KmsdCmd) Validate() error {
+	if len(k.Keys) == 0 {
+		return fmt.Errorf("key was empty")
+	}
+	if len(k.Allow)+len(k.Audiences) == 0 {
+		return fmt.Errorf("allow or audience key policy is required")
+	}
+	if (k.RSAKey == "") == (k.HMacKey == "") {
+		return fmt.Errorf("either rsa or hmac JWT verification key is required")
+	}
+	if (k.TLSCert == "") != (k.TLSKey == "") {
+		return fmt.Errorf("both tlsCert and tlsKey are required")
+	}
+	if k.TLSCert != "" && strings.HasPrefix(k.Listen, "unix://") {
+		return fmt.Errorf("TLS is not supported with unix socket")
+	}
+	return nil
+}
+
+// Execute runs the kmsd command
+func (k *KmsdCmd) Execute(args []string) error {
+	k.Init()
+	if err := k.Validate(); err != nil {
+		return err
+	}
+	return Kmsd(k)
+}
+
+// Kmsd runs kms daemon
+func Kmsd(kmsd *KmsdCmd) error {
+	ctx := context.Background()
+	keys := map[string]string{}
+	for _, item := range kmsd.Keys {
+		name, keyURL, ok := strings.Cut(item, "=")
+		if !ok || name == "" || keyURL == "" {
+			return fmt.Errorf("invalid key %q, expected name=URL", item)
+		}
+		keys[name] = keyURL
+	}
+	policies := map[string]*remote.Policy{}
+	policy := func(item, kind string) (*remote.Policy, string, error) {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || name == "" || value == "" {
+			return nil, "", fmt.Errorf("invalid %v %q, expected name=%v", kind, item, kind)
+		}
+		if policies[name] == nil {
+			policies[name] = &remote.Policy{}
+		}
+		return policies[name], value, nil
+	}
+	for _, item := range kmsd.Allow {
+		keyPolicy, value, err := policy(item, "subject")
+		if err != nil {
+			return err
+		}
+		keyPolicy.Subjects = append(keyPolicy.Subjects, value)
+	}
+	for _, item := range kmsd.Audiences {
+		keyPolicy, value, err := policy(item, "audience")
+		if err != nil {
+			return err
+		}
+		keyPolicy.Audiences = append(keyPolicy.Audiences, value)
+	}
+	config := &verifier.Config{}
+	if kmsd.RSAKey != "" {
+		config.RSA = []*scy.Resource{{URL: kmsd.RSAKey, Key: kmsd.AuthKey}}
+	} else {
+		config.HMAC = &scy.Resource{URL: kmsd.HMacKey, Key: kmsd.AuthKey}
+	}
+	jwtVerifier := verifier.New(config)
+	if err := jwtVerifier.Init(ctx); err != nil {
+		return err
+	}
+	server, err := remote.NewServer(jwtVerifier, keys, policies)
+	if err != nil {
+		return err
+	}
+	listener, err := remote.Listen(kmsd.Listen)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("kmsd: serving %v key(s) on %v\n", len(keys), kmsd.Listen)
+	httpServer := &http.Server{Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	if kmsd.TLSCert != "" {
+		return httpServer.ServeTLS(listener, kmsd.TLSCert, kmsd.TLSKey)
+	}
+	return httpServer.Serve(listener)
+}
@@ -12,6 +12,11 @@ type Options struct {
 	SignJwt   *SignJwtCmd    `command:"signJwt" description:"sign JWT"`
 	VerifyJwt *VerifyJwtCmd  `command:"verifyJwt" description:"verify JWT"`
 	Authorize *AuthorizeCmd  `command:"authorize" description:"authorize using OAuth2"`
+	Rekey     *RekeyCmd      `command:"rekey" description:"re-encrypts secrets with a new key"`
+	Split     *SplitCmd      `command:"split" description:"splits key into Shamir shares"`
+	Combine   *CombineCmd    `command:"combine" description:"combines Shamir shares into key"`
+	Kmsd      *KmsdCmd       `command:"kmsd" description:"serves encrypt/decrypt with held keys to JWT authenticated callers"`
+	Versions  *VersionsCmd   `command:"versions" description:"lists or rolls back secret versions"`
 }
 
 // Init normalizes file locations
@@ -32,5 +37,20 @@ func (options *Options) Init(args string) {
 	case "authorize":
 		options.Authorize = &AuthorizeCmd{}
 		options.Authorize.Init()
+	case "rekey":
+		options.Rekey = &RekeyCmd{}
+		options.Rekey.Init()
+	case "split":
+		options.Split = &SplitCmd{}
+		options.Split.Init()
+	case "combine":
+		options.Combine = &CombineCmd{}
+		options.Combine.Init()
+	case "kmsd":
+		options.Kmsd = &KmsdCmd{}
+		options.Kmsd.Init()
+	case "versions":
+		options.Versions = &VersionsCmd{}
+		options.Versions.Init()
 	}
 }
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/viant/scy"
+)
+
+// RekeyCmd command for re-encrypting secrets with a new key
+type RekeyCmd struct {
+	SourceURL      string `short:"s" long:"src" description:"secret or secrets folder location"`
+	Key            string `short:"k" long:"key" description:"current key i.e blowfish://default"`
+	NewKey         string `short:"n" long:"newKey" description:"new key i.e aes://env/SCY_KEY"`
+	DryRun         bool   `short:"d" long:"dryRun" description:"decrypt only, report secrets that would be re-encrypted"`
+	Force          bool   `short:"f" long:"force" description:"rekey secrets decrypted with a cipher that can not detect a wrong key i.e blowfish"`
+	BlowfishFormat int    `long:"blowfishFormat" choice:"1" choice:"2" description:"blowfish format of re-encrypted secrets, 2: random IV with PKCS#7 padding (binary safe)"`
+}
+
+// Init normalizes file locations
+func (r *RekeyCmd) Init() {
+	r.SourceURL = normalizeLocation(r.SourceURL)
+}
+
+// Validate validates the rekey command options
+func (r *RekeyCmd) Validate() error {
+	if r.SourceURL == "" {
+		return fmt.Errorf("src was empty")
+	}
+	if r.NewKey == "" {
+		return fmt.Errorf("newKey was empty")
+	}
+	return nil
+}
+
+// Execute runs the rekey command
+func (r *RekeyCmd) Execute(args []string) error {
+	r.Init()
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	return Rekey(r)
+}
+
+// Rekey re-encrypts secrets with a new key
+func Rekey(rekey *RekeyCmd) error {
+	useBlowfishFormat(rekey.BlowfishFormat)
+	srv := scy.New()
+	var options []scy.RekeyOption
+	if rekey.Force {
+		options = append(options, scy.WithUnauthenticatedRekey())
+	}
+	report, err := srv.RekeyAll(context.Background(), scy.NewResource("", rekey.SourceURL, rekey.Key), rekey.NewKey, rekey.DryRun, options...)
+	if err != nil {
+		return err
+	}
+	action := "rekeyed"
+	if report.DryRun {
+		action = "would rekey"
+	}
+	for _, URL := range report.Rekeyed {
+		fmt.Printf("%v: %v\n", action, URL)
+	}
+	for _, URL := range report.Skipped {
+		fmt.Printf("skipped: %v\n", URL)
+	}
+	for URL, failure := range report.Failed {
+		fmt.Printf("failed: %v: %v\n", URL, failure)
+	}
+	fmt.Printf("%v: %v, skipped: %v, failed: %v\n", action, len(report.Rekeyed), len(report.Skipped), len(report.Failed))
+	return report.Error()
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/viant/scy"
 	"github.com/viant/scy/cred"
 	"github.com/viant/toolbox"
+	"strings"
 )
 
 
@@ -14,6 +15,7 @@ import (
 type RevealCmd struct {
 	TypedSource
 	Key       string `short:"k" long:"key" description:"key i.e blowfish://default"`
+	Version   string `long:"version" description:"secret version, gcp secretmanager version or generation number"`
 }
 
 // Init normalizes file locations
@@ -42,6 +44,10 @@ func Reveal(reveal *RevealCmd) error {
 	if err != nil {
 		return err
 	}
+	if format := scy.Format(secret.URL); format == scy.FormatEnv || format == scy.FormatINI {
+		fmt.Println(strings.TrimSuffix(secret.String(), "\n"))
+		return nil
+	}
 	if !secret.IsPlain && secret.Target != nil {
 		aMap := map[string]interface{}{}
 		toolbox.DefaultConverter.AssignConverted(&aMap, secret.Target)
@@ -60,13 +66,14 @@ func loadSecret(cmd interface{}) (*scy.Secret, error) {
 	srv := scy.New()
 	var target interface{} = nil
 
-	var sourceURL, targetStr, keyStr string
+	var sourceURL, targetStr, keyStr, version string
 
 	switch v := cmd.(type) {
 	case *RevealCmd:
 		sourceURL = v.SourceURL
 		targetStr = v.Target
 		keyStr = v.Key
+		version = v.Version
 	case *AuthCmd:
 		sourceURL = v.SourceURL
 		targetStr = v.Target
@@ -81,6 +88,7 @@ func loadSecret(cmd interface{}) (*scy.Secret, error) {
 		target = targetType
 	}
 	resource := scy.NewResource(target, sourceURL, keyStr)
+	resource.Version = version
 	secret, err := srv.Load(context.Background(), resource)
 	if err != nil {
 		return nil, err
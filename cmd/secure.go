@@ -10,6 +10,9 @@ import (
 	"github.com/viant/scy"
 	"github.com/viant/scy/cred"
 	"github.com/viant/scy/cred/secret/term"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/age"
+	"github.com/viant/scy/kms/blowfish"
 	"golang.org/x/crypto/ssh/terminal"
 	"log"
 	"os"
@@ -19,8 +22,11 @@ import (
 
 type SecureCmd struct {
 	TypedSource
-	DestURL string `short:"d" long:"dest" description:"dest location"`
-	Key     string `short:"k" long:"key" description:"key i.e blowfish://default"`
+	DestURL        string   `short:"d" long:"dest" description:"dest location"`
+	Key            string   `short:"k" long:"key" description:"key i.e blowfish://default"`
+	Recipients     []string `short:"r" long:"recipient" description:"age recipient public key (age1...), can be repeated"`
+	BlowfishFormat int      `long:"blowfishFormat" choice:"1" choice:"2" description:"blowfish format, 1: legacy, 2: random IV with PKCS#7 padding (binary safe)"`
+	Generations    int      `long:"generations" description:"number of generation-suffixed copies (dest.v1, dest.v2...) to keep"`
 }
 
 // Execute runs the secure command
@@ -55,6 +61,13 @@ func (s *SecureCmd) Validate() error {
 
 // Secure secures secrets
 func Secure(secure *SecureCmd) error {
+	if len(secure.Recipients) > 0 {
+		if secure.Key != "" {
+			return fmt.Errorf("key and recipient are mutually exclusive")
+		}
+		secure.Key = age.RecipientsKey(secure.Recipients...)
+	}
+	useBlowfishFormat(secure.BlowfishFormat)
 	data, err := readSource(secure)
 	if err != nil {
 		log.Fatal(err)
@@ -68,12 +81,12 @@ func Secure(secure *SecureCmd) error {
 		target = targetType
 	}
 	srv := scy.New()
-
 	resource := scy.NewResource(target, secure.DestURL, secure.Key)
+	resource.Generations = secure.Generations
 	var secret *scy.Secret
 	if target != nil {
 		instance := reflect.New(target).Interface()
-		if err := json.Unmarshal(data, instance); err != nil {
+		if err := scy.Unmarshal(secure.SourceURL, data, instance); err != nil {
 			return err
 		}
 		switch actual := instance.(type) {
@@ -97,6 +110,9 @@ func Secure(secure *SecureCmd) error {
 			}
 		}
 		secret = scy.NewSecret(instance, resource)
+		if format := scy.Format(secure.SourceURL); format == scy.FormatEnv || format == scy.FormatINI {
+			secret.WithDocument(data)
+		}
 	} else {
 		secret = scy.NewSecret(string(data), resource)
 	}
@@ -177,3 +193,10 @@ func readSecret(timeout time.Duration) ([]byte, error) {
 	}
 	return rawSecret, nil
 }
+
+// useBlowfishFormat registers blowfish cipher writing supplied format, legacy and padded payloads are decrypted either way
+func useBlowfishFormat(format int) {
+	if format != 0 {
+		kms.Register(blowfish.Scheme, blowfish.New(format))
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/viant/afs"
+	"github.com/viant/afs/url"
+	"github.com/viant/scy/kms/shamir"
+	"strings"
+	"time"
+)
+
+// keyFileMode represents shares and key material file mode, readable by owner only
+const keyFileMode = 0600
+
+// SplitCmd command for splitting key material into Shamir shares
+type SplitCmd struct {
+	SourceURL string `short:"s" long:"src" description:"key material location, prompts when empty"`
+	DestURL   string `short:"d" long:"dest" description:"shares folder location, prints shares when empty"`
+	Shares    int    `short:"n" long:"shares" default:"5" description:"number of shares"`
+	Threshold int    `short:"t" long:"threshold" default:"3" description:"number of shares required to reconstruct key"`
+}
+
+// Init normalizes file locations
+func (s *SplitCmd) Init() {
+	s.SourceURL = normalizeLocation(s.SourceURL)
+	s.DestURL = normalizeLocation(s.DestURL)
+}
+
+// Validate validates the split command options
+func (s *SplitCmd) Validate() error {
+	if s.Threshold < 2 || s.Threshold > s.Shares {
+		return fmt.Errorf("invalid threshold: %v, expected 2 <= threshold <= shares(%v)", s.Threshold, s.Shares)
+	}
+	return nil
+}
+
+// Execute runs the split command
+func (s *SplitCmd) Execute(args []string) error {
+	s.Init()
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	return Split(s)
+}
+
+// Split splits key material into Shamir shares
+func Split(split *SplitCmd) error {
+	ctx := context.Background()
+	fs := afs.New()
+	var secret []byte
+	var err error
+	if split.SourceURL != "" {
+		secret, err = fs.DownloadWithURL(ctx, split.SourceURL)
+	} else {
+		secret, err = readSecret(time.Minute)
+		fmt.Println()
+	}
+	if err != nil {
+		return err
+	}
+	shares, err := shamir.Split(secret, split.Shares, split.Threshold)
+	if err != nil {
+		return err
+	}
+	for i, share := range shares {
+		if split.DestURL == "" {
+			fmt.Printf("share %v: %v\n", i+1, share.Encode())
+			continue
+		}
+		URL := url.Join(split.DestURL, fmt.Sprintf("share%v", i+1))
+		if err = fs.Upload(ctx, URL, keyFileMode, strings.NewReader(share.Encode()+"\n")); err != nil {
+			return err
+		}
+		fmt.Printf("share %v: %v\n", i+1, URL)
+	}
+	return nil
+}
+
+// CombineCmd command for reconstructing key material from Shamir shares
+type CombineCmd struct {
+	SourceURLs []string `short:"s" long:"src" description:"share location, can be repeated"`
+	DestURL    string   `short:"d" long:"dest" description:"key material location, prints key when empty"`
+}
+
+// Init normalizes file locations
+func (c *CombineCmd) Init() {
+	for i, location := range c.SourceURLs {
+		c.SourceURLs[i] = normalizeLocation(location)
+	}
+	c.DestURL = normalizeLocation(c.DestURL)
+}
+
+// Validate validates the combine command options
+func (c *CombineCmd) Validate() error {
+	if len(c.SourceURLs) == 0 {
+		return fmt.Errorf("src was empty")
+	}
+	return nil
+}
+
+// Execute runs the combine command
+func (c *CombineCmd) Execute(args []string) error {
+	c.Init()
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	return Combine(c)
+}
+
+// Combine reconstructs key material from Shamir shares
+func Combine(combine *CombineCmd) error {
+	ctx := context.Background()
+	fs := afs.New()
+	var shares []*shamir.Share
+	for _, location := range combine.SourceURLs {
+		data, err := fs.DownloadWithURL(ctx, location)
+		if err != nil {
+			return err
+		}
+		share, err := shamir.Decode(string(data))
+		if err != nil {
+			return fmt.Errorf("%v: %w", location, err)
+		}
+		shares = append(shares, share)
+	}
+	secret, err := shamir.Combine(shares)
+	if err != nil {
+		return err
+	}
+	if combine.DestURL == "" {
+		fmt.Println(string(secret))
+		return nil
+	}
+	return fs.Upload(ctx, combine.DestURL, keyFileMode, bytes.NewReader(secret))
+}
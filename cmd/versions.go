@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/viant/scy"
+)
+
+// VersionsCmd command for listing and rolling back secret versions
+type VersionsCmd struct {
+	SourceURL   string `short:"s" long:"src" description:"secret location"`
+	Rollback    string `short:"r" long:"rollback" description:"version to make current"`
+	Generations int    `long:"generations" description:"number of generation-suffixed copies to keep on rollback"`
+}
+
+// Init normalizes file locations
+func (v *VersionsCmd) Init() {
+	v.SourceURL = normalizeLocation(v.SourceURL)
+}
+
+// Validate validates the versions command options
+func (v *VersionsCmd) Validate() error {
+	if v.SourceURL == "" {
+		return fmt.Errorf("src was empty")
+	}
+	return nil
+}
+
+// Execute runs the versions command
+func (v *VersionsCmd) Execute(args []string) error {
+	v.Init()
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	return Versions(v)
+}
+
+// Versions lists secret versions or rolls back secret to a version
+func Versions(versions *VersionsCmd) error {
+	srv := scy.New()
+	resource := scy.NewResource(nil, versions.SourceURL, "")
+	resource.Generations = versions.Generations
+	if versions.Rollback != "" {
+		if err := srv.Rollback(context.Background(), resource, versions.Rollback); err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %v to version %v\n", versions.SourceURL, versions.Rollback)
+		return nil
+	}
+	list, err := srv.Versions(context.Background(), resource)
+	if err != nil {
+		return err
+	}
+	for _, version := range list {
+		fmt.Printf("%v\t%v\t%v\n", version.ID, version.Modified.Format("2006-01-02T15:04:05Z07:00"), version.Size)
+	}
+	return nil
+}
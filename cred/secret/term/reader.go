@@ -77,3 +77,42 @@ func readSecrets(nameLabel, secretLabel string, timeout time.Duration) (name str
 	secret = strings.TrimSpace(secret)
 	return name, secret, err
 }
+
+// ReadPassphrase reads passphrase from terminal, confirm requires passphrase to be retyped
+func ReadPassphrase(confirm bool, timeout time.Duration) (string, error) {
+	completed := make(chan bool, 1)
+	var passphrase string
+	var err error
+	var reader = func() {
+		defer func() {
+			completed <- true
+		}()
+		fmt.Print("Enter Passphrase: ")
+		var passphrase1Bytes, passphrase2Bytes []byte
+		if passphrase1Bytes, err = terminal.ReadPassword(int(os.Stdin.Fd())); err != nil {
+			err = fmt.Errorf("failed to read passphrase %v", err)
+			return
+		}
+		fmt.Println()
+		passphrase = string(passphrase1Bytes)
+		if !confirm {
+			return
+		}
+		fmt.Print("Retype Passphrase: ")
+		if passphrase2Bytes, err = terminal.ReadPassword(int(os.Stdin.Fd())); err != nil {
+			err = fmt.Errorf("failed to read passphrase %v", err)
+			return
+		}
+		fmt.Println()
+		if string(passphrase2Bytes) != passphrase {
+			err = fmt.Errorf("passphrase did not match")
+		}
+	}
+	go reader()
+	select {
+	case <-completed:
+	case <-time.After(timeout):
+		return "", fmt.Errorf("reading passphrase timeout")
+	}
+	return passphrase, err
+}
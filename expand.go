@@ -0,0 +1,185 @@
+package scy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Encoder encodes expanded placeholder value, i.e. ${Password|urlquery}
+type Encoder func(value string) (string, error)
+
+// Encoders represents built-in placeholder encoders
+var Encoders = map[string]Encoder{
+	"urlquery": func(value string) (string, error) { return url.QueryEscape(value), nil },
+	"shell":    func(value string) (string, error) { return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'", nil },
+	"json": func(value string) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+	"base64": func(value string) (string, error) { return base64.StdEncoding.EncodeToString([]byte(value)), nil },
+}
+
+type expandOptions struct {
+	strict   bool
+	encoders map[string]Encoder
+}
+
+// ExpandOption represents Secret.ExpandWith option
+type ExpandOption func(o *expandOptions)
+
+// WithStrictExpansion fails expansion on unresolved placeholders
+func WithStrictExpansion() ExpandOption {
+	return func(o *expandOptions) {
+		o.strict = true
+	}
+}
+
+// WithEncoder registers custom placeholder encoder for an expansion
+func WithEncoder(name string, encoder Encoder) ExpandOption {
+	return func(o *expandOptions) {
+		o.encoders[name] = encoder
+	}
+}
+
+// ExpandWith expands text like Expand (toolbox/data map ExpandAsText, i.e. $name, ${cred.Password}), ${name|encoder...}
+// placeholders are replaced with the Expand value of ${name} encoded from left to right; unresolved placeholders are left
+// as is unless WithStrictExpansion is used, in which case all of them are reported
+func (s *Secret) ExpandWith(text string, options ...ExpandOption) (string, error) {
+	opts := &expandOptions{encoders: map[string]Encoder{}}
+	for name, encoder := range Encoders {
+		opts.encoders[name] = encoder
+	}
+	for _, option := range options {
+		option(opts)
+	}
+	replacement := s.expansionMap()
+	var unresolved []string
+	var encoded []*placeholder
+	var err error
+	scanPlaceholders(text, func(placeholder *placeholder) string {
+		if err != nil {
+			return placeholder.text
+		}
+		expression := placeholder.text
+		if len(placeholder.encoders) > 0 {
+			expression = "${" + placeholder.name + "}"
+		}
+		expanded := replacement.ExpandAsText(expression)
+		if expanded == expression {
+			unresolved = append(unresolved, placeholder.name)
+			return placeholder.text
+		}
+		if len(placeholder.encoders) == 0 {
+			return placeholder.text //expanded with the rest of the text
+		}
+		for _, name := range placeholder.encoders {
+			encoder, ok := opts.encoders[name]
+			if !ok {
+				err = fmt.Errorf("unsupported encoder %v: %v", name, placeholder.text)
+				return placeholder.text
+			}
+			if expanded, err = encoder(expanded); err != nil {
+				err = fmt.Errorf("failed to encode %v: %w", placeholder.text, err)
+				return placeholder.text
+			}
+		}
+		placeholder.value = expanded
+		encoded = append(encoded, placeholder)
+		return placeholder.text
+	})
+	if err != nil {
+		return "", err
+	}
+	if opts.strict && len(unresolved) > 0 {
+		return "", fmt.Errorf("unresolved placeholders: %v", strings.Join(unresolved, ", "))
+	}
+	var result strings.Builder
+	offset := 0
+	for _, item := range encoded { //text between encoded placeholders is expanded, encoded values are written as is
+		result.WriteString(replacement.ExpandAsText(text[offset:item.offset]))
+		result.WriteString(item.value)
+		offset = item.offset + len(item.text)
+	}
+	result.WriteString(replacement.ExpandAsText(text[offset:]))
+	return result.String(), nil
+}
+
+// Placeholders returns unique placeholder names used by text in order of appearance, encoders are not included
+func Placeholders(text string) []string {
+	var result []string
+	var seen = map[string]bool{}
+	scanPlaceholders(text, func(placeholder *placeholder) string {
+		if !seen[placeholder.name] {
+			seen[placeholder.name] = true
+			result = append(result, placeholder.name)
+		}
+		return placeholder.text
+	})
+	return result
+}
+
+type placeholder struct {
+	text     string
+	offset   int //placeholder position in scanned text
+	name     string
+	encoders []string
+	value    string
+}
+
+// scanPlaceholders replaces $name and ${name|encoder} placeholders with expand result, other text is copied as is
+func scanPlaceholders(text string, expand func(placeholder *placeholder) string) string {
+	var result strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' || i+1 == len(text) {
+			result.WriteByte(text[i])
+			continue
+		}
+		if text[i+1] == '{' {
+			end := strings.IndexByte(text[i+2:], '}')
+			if end == -1 {
+				result.WriteByte(text[i])
+				continue
+			}
+			body := text[i+2 : i+2+end]
+			elements := strings.Split(body, "|")
+			item := &placeholder{text: text[i : i+3+end], offset: i, name: strings.TrimSpace(elements[0])}
+			for _, encoder := range elements[1:] {
+				item.encoders = append(item.encoders, strings.TrimSpace(encoder))
+			}
+			if item.name == "" {
+				result.WriteString(item.text)
+			} else {
+				result.WriteString(expand(item))
+			}
+			i += 2 + end
+			continue
+		}
+		end := i + 1
+		for end < len(text) && isPlaceholderChar(text[end], end == i+1) {
+			end++
+		}
+		for end > i+1 && text[end-1] == '.' { //trailing dot is not part of the name
+			end--
+		}
+		if end == i+1 {
+			result.WriteByte(text[i])
+			continue
+		}
+		result.WriteString(expand(&placeholder{text: text[i:end], offset: i, name: text[i+1 : end]}))
+		i = end - 1
+	}
+	return result.String()
+}
+
+func isPlaceholderChar(c byte, first bool) bool {
+	switch {
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return true
+	case c >= '0' && c <= '9', c == '.':
+		return !first
+	}
+	return false
+}
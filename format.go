@@ -0,0 +1,96 @@
+package scy
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Payload formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatEnv  = "env"
+	FormatINI  = "ini"
+)
+
+// Format returns payload format inferred from URL: .yml/.yaml, .env (including .env.<stage> files), .ini or .json,
+// empty format means that JSON payload is only detected by content
+func Format(URL string) string {
+	name := strings.ToLower(path.Base(strings.TrimRight(URL, "/")))
+	switch {
+	case name == ".env" || strings.HasPrefix(name, ".env.") || path.Ext(name) == ".env":
+		return FormatEnv
+	case path.Ext(name) == ".ini":
+		return FormatINI
+	case path.Ext(name) == ".yml" || path.Ext(name) == ".yaml":
+		return FormatYAML
+	case path.Ext(name) == ".json":
+		return FormatJSON
+	}
+	return ""
+}
+
+// Unmarshal decodes JSON, YAML, dotenv or INI payload into target, format is inferred from URL
+func Unmarshal(URL string, data []byte, target interface{}) error {
+	switch format := Format(URL); format {
+	case FormatYAML:
+		return yaml.Unmarshal(data, target)
+	case FormatEnv, FormatINI:
+		if !isJson(data) {
+			return unmarshalText(data, format == FormatINI, target)
+		}
+	}
+	return json.Unmarshal(data, target)
+}
+
+// isTextDocument returns true if data is a valid dotenv or INI document of supplied format
+func isTextDocument(format string, data []byte) bool {
+	if format != FormatEnv && format != FormatINI {
+		return false
+	}
+	if !utf8.Valid(data) || isJson(data) {
+		return false
+	}
+	_, err := parseTextDocument(data, format == FormatINI)
+	return err == nil
+}
+
+// unmarshalText decodes dotenv or INI document into target, keys are matched with fields like JSON keys (case-insensitively)
+func unmarshalText(data []byte, ini bool, target interface{}) error {
+	doc, err := parseTextDocument(data, ini)
+	if err != nil {
+		return err
+	}
+	values, err := doc.Map()
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, target)
+}
+
+// marshalText renders target into base document, comments, ordering and keys unknown to target are preserved
+func marshalText(base []byte, ini bool, target interface{}) ([]byte, error) {
+	doc, err := parseTextDocument(base, ini)
+	if err != nil {
+		if doc, err = parseTextDocument(nil, ini); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := json.Marshal(target)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err = json.Unmarshal(encoded, &values); err != nil {
+		return nil, err
+	}
+	doc.Apply(values)
+	return doc.Bytes(), nil
+}
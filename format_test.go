@@ -0,0 +1,169 @@
+package scy_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/cred"
+	"github.com/viant/scy/kms/memory"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestService_Env(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("env")
+	document := `# database credentials
+export USERNAME=app
+PASSWORD="s3cr3t" # rotated quarterly
+DB_HOST=localhost
+
+API_URL='https://example.com/?a=1&b=2'
+`
+	URL := path.Join(t.TempDir(), ".env")
+	assert.Nil(t, os.WriteFile(URL, []byte(document), 0600))
+	srv := scy.New()
+
+	secret, err := srv.Load(ctx, scy.NewResource("", URL, ""))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, secret.IsPlain)
+	assert.EqualValues(t, &cred.Generic{SSH: cred.SSH{Basic: cred.Basic{Username: "app", Password: "s3cr3t"}}}, secret.Target)
+	assert.EqualValues(t, document, secret.String())
+	assert.EqualValues(t, "localhost:https://example.com/?a=1&b=2", secret.Expand("$DB_HOST:$API_URL"))
+
+	secret.Resource = scy.NewResource(cred.Generic{}, URL, key)
+	if !assert.Nil(t, srv.Store(ctx, secret)) {
+		return
+	}
+	stored, err := os.ReadFile(URL)
+	assert.Nil(t, err)
+	lines, expectLines := strings.Split(string(stored), "\n"), strings.Split(document, "\n")
+	if assert.Len(t, lines, len(expectLines)) {
+		assert.True(t, strings.HasPrefix(lines[2], `ENCRYPTEDPASSWORD="`), lines[2])
+		assert.True(t, strings.HasSuffix(lines[2], `" # rotated quarterly`), lines[2])
+		lines[2] = expectLines[2]
+		assert.EqualValues(t, expectLines, lines, "field is encrypted in place")
+	}
+	assert.NotContains(t, string(stored), "s3cr3t")
+
+	secret, err = srv.Load(ctx, scy.NewResource(cred.Generic{}, URL, key))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "s3cr3t", secret.Target.(*cred.Generic).Password)
+		assert.EqualValues(t, document, secret.String(), "deciphered document keeps comments and ordering")
+	}
+
+	rotated := cipher.Key("rotated")
+	assert.Nil(t, srv.Rekey(ctx, scy.NewResource("", URL, key), rotated))
+	secret, err = srv.Load(ctx, scy.NewResource(cred.Generic{}, URL, rotated))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, document, secret.String(), "rekeyed document")
+	}
+}
+
+func TestService_INI(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("ini")
+	document := `; service settings
+name = billing
+
+[database]
+user = app ; read write user
+password = "p#ss"
+`
+	var testCases = []struct {
+		description string
+		key         string
+	}{
+		{description: "plain document"},
+		{description: "whole file encryption", key: key},
+	}
+	for _, testCase := range testCases {
+		URL := path.Join(t.TempDir(), "app.ini")
+		srv := scy.New()
+		if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(document, scy.NewResource("", URL, testCase.key))), testCase.description) {
+			continue
+		}
+		secret, err := srv.Load(ctx, scy.NewResource(map[string]interface{}{}, URL, testCase.key))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		expect := map[string]interface{}{"name": "billing", "database": map[string]interface{}{"user": "app", "password": "p#ss"}}
+		assert.EqualValues(t, &expect, secret.Target, testCase.description)
+		assert.EqualValues(t, document, secret.String(), testCase.description)
+
+		target := secret.Target.(*map[string]interface{})
+		(*target)["database"].(map[string]interface{})["host"] = "db.local"
+		(*target)["timeout"] = "5s"
+		assert.Nil(t, srv.Store(ctx, secret), testCase.description)
+		secret, err = srv.Load(ctx, scy.NewResource("", URL, testCase.key))
+		if assert.Nil(t, err, testCase.description) {
+			assert.EqualValues(t, `; service settings
+name = billing
+timeout = 5s
+
+[database]
+user = app ; read write user
+password = "p#ss"
+host = db.local
+`, secret.String(), testCase.description)
+		}
+	}
+}
+
+func TestService_Env_Clear(t *testing.T) {
+	ctx := context.Background()
+	document := `USERNAME=app
+PASSWORD="s3cr3t" # rotated quarterly
+TOKEN=abc
+`
+	URL := path.Join(t.TempDir(), ".env")
+	assert.Nil(t, os.WriteFile(URL, []byte(document), 0600))
+	srv := scy.New()
+	secret, err := srv.Load(ctx, scy.NewResource(map[string]interface{}{}, URL, ""))
+	if !assert.Nil(t, err) {
+		return
+	}
+	target := secret.Target.(*map[string]interface{})
+	(*target)["PASSWORD"] = ""
+	(*target)["EMPTY"] = ""
+	delete(*target, "TOKEN")
+	if !assert.Nil(t, srv.Store(ctx, secret)) {
+		return
+	}
+	stored, err := os.ReadFile(URL)
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, `USERNAME=app
+PASSWORD="" # rotated quarterly
+TOKEN=abc
+`, string(stored), "present empty value clears entry, missing key and new empty value leave document intact")
+	}
+	secret, err = srv.Load(ctx, scy.NewResource(map[string]interface{}{}, URL, ""))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, &map[string]interface{}{"USERNAME": "app", "PASSWORD": "", "TOKEN": "abc"}, secret.Target)
+	}
+}
+
+func TestService_INI_Conflict(t *testing.T) {
+	ctx := context.Background()
+	var testCases = []struct {
+		description string
+		document    string
+	}{
+		{description: "key before section", document: "database = main\n\n[database]\nuser = app\n"},
+		{description: "key after section", document: "[database]\nuser = app\n\n[]\ndatabase = main\n"},
+	}
+	for _, testCase := range testCases {
+		URL := path.Join(t.TempDir(), "app.ini")
+		assert.Nil(t, os.WriteFile(URL, []byte(testCase.document), 0600), testCase.description)
+		_, err := scy.New().Load(ctx, scy.NewResource(map[string]interface{}{}, URL, ""))
+		if assert.NotNil(t, err, testCase.description) {
+			assert.Contains(t, err.Error(), "conflicts with section [database]", testCase.description)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package aes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"github.com/viant/scy/kms"
+	"io"
+)
+
+// Scheme represents aes cipher scheme
+const Scheme = "aes"
+
+// KeySize represents AES-256 key size
+const KeySize = 32
+
+var defaultKey = []byte{
+	0x5B, 0x1E, 0xA7, 0x3C, 0x94, 0x0F, 0xD2, 0x68,
+	0x2A, 0xE1, 0x77, 0xB3, 0x4C, 0x89, 0x15, 0xF0,
+	0x63, 0xDE, 0x0A, 0x91, 0x3F, 0xC5, 0x58, 0xB7,
+	0x26, 0x7D, 0xE8, 0x42, 0x9A, 0x1B, 0xCF, 0x04,
+}
+
+// EnsureKey returns a 32-byte AES-256 key.
+// If src is already 32 bytes it’s used verbatim.
+// Otherwise a 32-byte SHA-256 digest is returned.
+func EnsureKey(src []byte) []byte {
+	if len(src) == KeySize {
+		return src
+	}
+	sum := sha256.Sum256(src)
+	return sum[:]
+}
+
+// Cipher represents AES-256-GCM cipher
+type Cipher struct{}
+
+// Algorithm returns cipher algorithm name
+func (c *Cipher) Algorithm() string {
+	return "aes-256-gcm"
+}
+
+// Authenticated returns true, decryption verifies ciphertext integrity
+func (c *Cipher) Authenticated() bool {
+	return true
+}
+
+// BindsAssociatedData returns true, context associated data is authenticated with ciphertext
+func (c *Cipher) BindsAssociatedData() bool {
+	return true
+}
+
+// Encrypt encrypts data with supplied key, the result is nonce followed by sealed data
+func (c *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	cipherKey, prefix, err := key.EncryptionKey(ctx, defaultKey, KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(key, cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	result := make([]byte, 0, len(prefix)+len(nonce)+len(data)+aead.Overhead())
+	result = append(append(result, prefix...), nonce...)
+	return aead.Seal(result, nonce, data, kms.AssociatedData(ctx)), nil
+}
+
+// Decrypt decrypts data with supplied key
+func (c *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	cipherKey, data, err := key.DecryptionKey(ctx, defaultKey, data, KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(key, cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("invalid ciphertext length: %v", len(data))
+	}
+	if kms.IsStream(data) {
+		if reader, err := kms.NewStreamReader(aead, bytes.NewReader(data), kms.AssociatedData(ctx)); err == nil {
+			if result, err := io.ReadAll(reader); err == nil {
+				return result, nil
+			}
+		}
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	result, err := aead.Open(nil, nonce, sealed, kms.AssociatedData(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %v: %w", key.Name(), err)
+	}
+	return result, nil
+}
+
+// EncryptWriter returns writer encrypting data into w with chunked AES-256-GCM, Close seals the final chunk
+func (c *Cipher) EncryptWriter(ctx context.Context, key *kms.Key, w io.Writer) (io.WriteCloser, error) {
+	cipherKey, prefix, err := key.EncryptionKey(ctx, defaultKey, KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(key, cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return kms.NewStreamWriter(aead, w, kms.DefaultChunkSize, kms.AssociatedData(ctx))
+}
+
+// DecryptReader returns reader decrypting data from r, payloads encrypted with Encrypt are read fully
+func (c *Cipher) DecryptReader(ctx context.Context, key *kms.Key, r io.Reader) (io.Reader, error) {
+	cipherKey, r, err := key.DecryptionKeyReader(ctx, defaultKey, r, KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(key, cipherKey)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(len(kms.StreamMagic)); bytes.Equal(magic, kms.StreamMagic) {
+		return kms.NewStreamReader(aead, reader, kms.AssociatedData(ctx))
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("invalid ciphertext length: %v", len(data))
+	}
+	result, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], kms.AssociatedData(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %v: %w", key.Name(), err)
+	}
+	return bytes.NewReader(result), nil
+}
+
+func (c *Cipher) aead(key *kms.Key, cipherKey []byte) (cipher.AEAD, error) {
+	aead, err := kms.CachedCipher(key, Scheme, cipherKey, func(material []byte) (interface{}, error) {
+		block, err := aes.NewCipher(EnsureKey(material))
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return aead.(cipher.AEAD), nil
+}
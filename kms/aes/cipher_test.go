@@ -0,0 +1,152 @@
+package aes_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	_ "github.com/viant/scy/kms/aes"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCipher_Encrypt(t *testing.T) {
+	keyFile := path.Join(os.TempDir(), "aes_test.key")
+	_ = os.WriteFile(keyFile, []byte("file based key material"), 0600)
+	defer os.Remove(keyFile)
+
+	var testCases = []struct {
+		description string
+		key         string
+		input       string
+		envKey      string
+		envValue    string
+	}{
+		{
+			description: "default key",
+			key:         "aes://default",
+			input:       "secret sequence @123!@#",
+		},
+		{
+			description: "env key",
+			key:         "aes://env/myAesKey",
+			input:       "123456789!@#$%^&*",
+			envKey:      "myAesKey",
+			envValue:    "this is my key",
+		},
+		{
+			description: "file key",
+			key:         "aes://localhost" + keyFile,
+			input:       "file secret",
+		},
+		{
+			description: "passphrase key",
+			key:         "aes://pass/env/myAesPassphrase",
+			input:       "passphrase protected secret",
+			envKey:      "myAesPassphrase",
+			envValue:    "correct horse battery staple",
+		},
+		{
+			description: "binary payload",
+			key:         "aes://default",
+			input:       string([]byte{0x0, 0x1, 0x0, 0xFF, 0x0}),
+		},
+	}
+
+	for _, testCase := range testCases {
+		if testCase.envKey != "" {
+			_ = os.Setenv(testCase.envKey, testCase.envValue)
+		}
+		key, err := kms.NewKey(testCase.key)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		srv, err := kms.Lookup(key.Scheme)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		ctx := context.Background()
+		encrypted, err := srv.Encrypt(ctx, key, []byte(testCase.input))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		actual, err := srv.Decrypt(ctx, key, encrypted)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, testCase.input, string(actual), testCase.description)
+	}
+}
+
+func TestCipher_Decrypt(t *testing.T) {
+	ctx := context.Background()
+	_ = os.Setenv("otherAesKey", "some other key")
+	key, _ := kms.NewKey("aes://default")
+	otherKey, _ := kms.NewKey("aes://env/otherAesKey")
+	srv, _ := kms.Lookup(key.Scheme)
+	encrypted, err := srv.Encrypt(ctx, key, []byte("secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = srv.Decrypt(ctx, otherKey, encrypted)
+	assert.NotNil(t, err, "wrong key")
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 0x1
+	_, err = srv.Decrypt(ctx, key, tampered)
+	assert.NotNil(t, err, "tampered payload")
+
+	_, err = srv.Decrypt(ctx, key, encrypted[:4])
+	assert.NotNil(t, err, "truncated payload")
+
+	_ = os.Setenv("aesPassphrase", "passphrase 1")
+	passKey, _ := kms.NewKey("aes://pass/env/aesPassphrase")
+	protected, err := srv.Encrypt(ctx, passKey, []byte("secret"))
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(protected, kms.PassphraseMagic), "salt stored with ciphertext")
+	_ = os.Setenv("aesPassphrase", "passphrase 2")
+	_, err = srv.Decrypt(ctx, passKey, protected)
+	assert.NotNil(t, err, "wrong passphrase")
+
+	inlineKey, _ := kms.NewKey("aes://inline/myInlineKeyMaterial")
+	sealed, err := srv.Encrypt(ctx, inlineKey, []byte("secret"))
+	assert.Nil(t, err)
+	wrongKey, _ := kms.NewKey("aes://inline/otherInlineKeyMaterial")
+	_, err = srv.Decrypt(ctx, wrongKey, sealed)
+	if assert.NotNil(t, err, "wrong inline key") {
+		assert.NotContains(t, err.Error(), "otherInlineKeyMaterial", "key material is not reported")
+	}
+	_, err = srv.(kms.StreamCipher).DecryptReader(ctx, wrongKey, bytes.NewReader(sealed))
+	if assert.NotNil(t, err, "wrong inline key") {
+		assert.NotContains(t, err.Error(), "otherInlineKeyMaterial", "key material is not reported")
+	}
+
+	again, err := srv.Encrypt(ctx, key, []byte("secret"))
+	assert.Nil(t, err)
+	assert.NotEqual(t, encrypted, again, "random nonce")
+}
+
+func TestCipher_AssociatedData(t *testing.T) {
+	key, err := kms.NewKey("aes://default")
+	if !assert.Nil(t, err) {
+		return
+	}
+	cipher, _ := kms.Lookup(key.Scheme)
+	assert.True(t, kms.BindsAssociatedData(cipher))
+	ctx := kms.WithAssociatedData(context.Background(), []byte("file:///tmp/a.sec"))
+	data := []byte("bound secret")
+	encrypted, err := cipher.Encrypt(ctx, key, data)
+	if !assert.Nil(t, err) {
+		return
+	}
+	decrypted, err := cipher.Decrypt(ctx, key, encrypted)
+	assert.Nil(t, err)
+	assert.EqualValues(t, data, decrypted)
+
+	_, err = cipher.Decrypt(kms.WithAssociatedData(context.Background(), []byte("file:///tmp/b.sec")), key, encrypted)
+	assert.NotNil(t, err, "different associated data")
+	_, err = cipher.Decrypt(context.Background(), key, encrypted)
+	assert.NotNil(t, err, "missing associated data")
+}
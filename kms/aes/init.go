@@ -0,0 +1,7 @@
+package aes
+
+import "github.com/viant/scy/kms"
+
+func init() {
+	kms.Register(Scheme, &Cipher{})
+}
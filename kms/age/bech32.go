@@ -0,0 +1,118 @@
+package age
+
+import (
+	"fmt"
+	"strings"
+)
+
+const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var generator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+func polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	h := []byte(strings.ToLower(hrp))
+	var ret []byte
+	for _, c := range h {
+		ret = append(ret, c>>5)
+	}
+	ret = append(ret, 0)
+	for _, c := range h {
+		ret = append(ret, c&31)
+	}
+	return ret
+}
+
+func convertBits(data []byte, fromBits, toBits byte, pad bool) ([]byte, error) {
+	var ret []byte
+	acc := uint32(0)
+	bits := byte(0)
+	maxv := byte(1<<toBits - 1)
+	for _, b := range data {
+		if b>>fromBits != 0 {
+			return nil, fmt.Errorf("invalid data range: %d", b)
+		}
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			ret = append(ret, byte(acc>>bits)&maxv)
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(toBits-bits))&maxv)
+		}
+	} else if bits >= fromBits {
+		return nil, fmt.Errorf("illegal zero padding")
+	} else if byte(acc<<(toBits-bits))&maxv != 0 {
+		return nil, fmt.Errorf("non-zero padding")
+	}
+	return ret, nil
+}
+
+// bech32Encode encodes data with supplied human readable part, result case follows hrp case
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	lower := strings.ToLower(hrp)
+	combined := append(hrpExpand(lower), values...)
+	mod := polymod(append(combined, 0, 0, 0, 0, 0, 0)) ^ 1
+	var builder strings.Builder
+	builder.WriteString(lower)
+	builder.WriteByte('1')
+	for _, v := range values {
+		builder.WriteByte(charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		builder.WriteByte(charset[(mod>>uint(5*(5-i)))&31])
+	}
+	if hrp != lower {
+		return strings.ToUpper(builder.String()), nil
+	}
+	return builder.String(), nil
+}
+
+// bech32Decode decodes bech32 string into human readable part and data
+func bech32Decode(value string) (string, []byte, error) {
+	if strings.ToLower(value) != value && strings.ToUpper(value) != value {
+		return "", nil, fmt.Errorf("mixed case")
+	}
+	value = strings.ToLower(value)
+	pos := strings.LastIndex(value, "1")
+	if pos < 1 || pos+7 > len(value) {
+		return "", nil, fmt.Errorf("separator '1' at invalid position")
+	}
+	hrp := value[:pos]
+	var values []byte
+	for _, c := range value[pos+1:] {
+		index := strings.IndexRune(charset, c)
+		if index == -1 {
+			return "", nil, fmt.Errorf("invalid character %q", c)
+		}
+		values = append(values, byte(index))
+	}
+	if polymod(append(hrpExpand(hrp), values...)) != 1 {
+		return "", nil, fmt.Errorf("invalid checksum")
+	}
+	data, err := convertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,132 @@
+package age
+
+import (
+	"context"
+	"fmt"
+	"github.com/viant/scy/kms"
+	"strings"
+)
+
+// Scheme represents age cipher scheme
+const Scheme = "age"
+
+// Cipher represents age X25519 multi-recipient cipher.
+// Key material lists age1 recipients and/or AGE-SECRET-KEY-1 identities separated by new line, comma or space,
+// data is encrypted to all recipients (including identities' recipients) and decrypted with identities.
+type Cipher struct{}
+
+// Algorithm returns cipher algorithm name
+func (c *Cipher) Algorithm() string {
+	return "age-x25519"
+}
+
+// Authenticated returns true, decryption verifies ciphertext integrity
+func (c *Cipher) Authenticated() bool {
+	return true
+}
+
+// Encrypt encrypts data to key recipients
+func (c *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	recipients, _, err := c.keyMaterial(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("age key %v has no recipients", key.Raw)
+	}
+	return Encrypt(data, recipients...)
+}
+
+// Decrypt decrypts data with key identities
+func (c *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	_, identities, err := c.keyMaterial(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("age key %v has no identities", key.Path)
+	}
+	return Decrypt(data, identities...)
+}
+
+// Rewrap re-wraps data file key for supplied recipients using key identities, payload is not re-encrypted, ciphertext header is preserved
+func (c *Cipher) Rewrap(ctx context.Context, key *kms.Key, data []byte, recipients ...string) ([]byte, error) {
+	_, identities, err := c.keyMaterial(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var parsed []*Recipient
+	for _, candidate := range recipients {
+		recipient, err := ParseRecipient(candidate)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, recipient)
+	}
+	header, body, err := kms.DecodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if body, err = Rewrap(body, identities, parsed); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return body, nil
+	}
+	return header.Encode(body)
+}
+
+func (c *Cipher) keyMaterial(ctx context.Context, key *kms.Key) ([]*Recipient, []*Identity, error) {
+	material, err := key.Key(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(material) == 0 {
+		return nil, nil, fmt.Errorf("age key %v was empty", key.Raw)
+	}
+	return parseKeyMaterial(string(material))
+}
+
+func parseKeyMaterial(material string) ([]*Recipient, []*Identity, error) {
+	var recipients []*Recipient
+	var identities []*Identity
+	seen := map[string]bool{}
+	addRecipient := func(recipient *Recipient) {
+		if encoded := recipient.String(); !seen[encoded] {
+			seen[encoded] = true
+			recipients = append(recipients, recipient)
+		}
+	}
+	for _, line := range strings.Split(material, "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, token := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			token = strings.Trim(token, "/")
+			switch {
+			case token == "":
+			case strings.HasPrefix(token, identityHRP+"1"):
+				identity, err := ParseIdentity(token)
+				if err != nil {
+					return nil, nil, err
+				}
+				identities = append(identities, identity)
+				addRecipient(identity.Recipient())
+			case strings.HasPrefix(token, recipientHRP+"1"):
+				recipient, err := ParseRecipient(token)
+				if err != nil {
+					return nil, nil, err
+				}
+				addRecipient(recipient)
+			default:
+				return nil, nil, fmt.Errorf("unsupported age key token, expected age1 recipient or AGE-SECRET-KEY-1 identity")
+			}
+		}
+	}
+	return recipients, identities, nil
+}
+
+// RecipientsKey returns inline key reference encrypting to supplied recipients
+func RecipientsKey(recipients ...string) string {
+	return Scheme + "://inline/" + strings.Join(recipients, ",")
+}
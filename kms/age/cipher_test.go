@@ -0,0 +1,135 @@
+package age_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/age"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCipher_Encrypt(t *testing.T) {
+	ctx := context.Background()
+	alice, _ := age.GenerateIdentity()
+	bob, _ := age.GenerateIdentity()
+	carol, _ := age.GenerateIdentity()
+	_ = os.Setenv("ageAlice", alice.String())
+	_ = os.Setenv("ageBob", bob.String())
+	_ = os.Setenv("ageCarol", carol.String())
+
+	var testCases = []struct {
+		description string
+		recipients  []*age.Identity
+		input       string
+		decryptKeys []string
+		deniedKeys  []string
+	}{
+		{
+			description: "multiple recipients",
+			recipients:  []*age.Identity{alice, bob},
+			input:       "secret sequence @123!@#",
+			decryptKeys: []string{"age://env/ageAlice", "age://env/ageBob"},
+			deniedKeys:  []string{"age://env/ageCarol"},
+		},
+		{
+			description: "large binary payload",
+			recipients:  []*age.Identity{carol},
+			input:       string(make([]byte, 200000)),
+			decryptKeys: []string{"age://env/ageCarol"},
+			deniedKeys:  []string{"age://env/ageAlice"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		var recipients []string
+		for _, identity := range testCase.recipients {
+			recipients = append(recipients, identity.Recipient().String())
+		}
+		key, err := kms.NewKey(age.RecipientsKey(recipients...))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		srv, err := kms.Lookup(key.Scheme)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		encrypted, err := srv.Encrypt(ctx, key, []byte(testCase.input))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		for _, decryptKey := range testCase.decryptKeys {
+			identityKey, _ := kms.NewKey(decryptKey)
+			actual, err := srv.Decrypt(ctx, identityKey, encrypted)
+			if !assert.Nil(t, err, testCase.description+" "+decryptKey) {
+				continue
+			}
+			assert.EqualValues(t, testCase.input, string(actual), testCase.description)
+		}
+		for _, deniedKey := range testCase.deniedKeys {
+			identityKey, _ := kms.NewKey(deniedKey)
+			_, err := srv.Decrypt(ctx, identityKey, encrypted)
+			assert.NotNil(t, err, testCase.description+" "+deniedKey)
+		}
+	}
+}
+
+func TestCipher_Rewrap(t *testing.T) {
+	ctx := context.Background()
+	alice, _ := age.GenerateIdentity()
+	bob, _ := age.GenerateIdentity()
+	_ = os.Setenv("ageRewrapAlice", alice.String())
+	_ = os.Setenv("ageRewrapBob", bob.String())
+	aliceKey, _ := kms.NewKey("age://env/ageRewrapAlice")
+	bobKey, _ := kms.NewKey("age://env/ageRewrapBob")
+	cipher := &age.Cipher{}
+
+	srv := scy.New()
+	resource := scy.NewResource("key", path.Join(os.TempDir(), "age.sec"), age.RecipientsKey(alice.Recipient().String()))
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("team secret", resource))) {
+		return
+	}
+	stored, _ := os.ReadFile(resource.URL)
+	_, err := cipher.Decrypt(ctx, bobKey, stored)
+	assert.NotNil(t, err, "bob is not a recipient yet")
+
+	rewrapped, err := cipher.Rewrap(ctx, aliceKey, stored, bob.Recipient().String())
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, storedBody, _ := kms.DecodeHeader(stored)
+	_, rewrappedBody, _ := kms.DecodeHeader(rewrapped)
+	assert.EqualValues(t, storedBody[len(storedBody)-40:], rewrappedBody[len(rewrappedBody)-40:], "payload preserved")
+	_ = os.WriteFile(resource.URL, rewrapped, 0600)
+
+	secret, err := srv.Load(ctx, scy.NewResource("key", resource.URL, "age://env/ageRewrapBob"))
+	if assert.Nil(t, err, "bob added") {
+		assert.EqualValues(t, "team secret", secret.Target)
+	}
+	_, err = srv.Load(ctx, scy.NewResource("key", resource.URL, "age://env/ageRewrapAlice"))
+	assert.NotNil(t, err, "alice removed")
+}
+
+func TestCipher_Decrypt(t *testing.T) {
+	ctx := context.Background()
+	key, err := kms.NewKey("age://localhost/" + path.Join(currentDir(), "testdata/identity.txt"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	data, err := os.ReadFile("testdata/secret.age")
+	if !assert.Nil(t, err) {
+		return
+	}
+	actual, err := (&age.Cipher{}).Decrypt(ctx, key, data)
+	if !assert.Nil(t, err, "age encrypted payload") {
+		return
+	}
+	assert.EqualValues(t, "encrypted with age v1.1.1", string(actual))
+}
+
+func currentDir() string {
+	dir, _ := os.Getwd()
+	return dir
+}
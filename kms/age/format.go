@@ -0,0 +1,313 @@
+package age
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"strings"
+)
+
+const (
+	intro        = "age-encryption.org/v1\n"
+	stanzaPrefix = "-> "
+	footerPrefix = "---"
+	columns      = 64
+	chunkSize    = 64 * 1024
+	nonceSize    = 16
+)
+
+var errIncorrectIdentity = errors.New("incorrect identity for recipient stanza")
+
+// stanza represents age header recipient stanza
+type stanza struct {
+	Type string
+	Args []string
+	Body []byte
+}
+
+func (s *stanza) encode(w *bytes.Buffer) {
+	w.WriteString(stanzaPrefix + strings.Join(append([]string{s.Type}, s.Args...), " ") + "\n")
+	body := b64.EncodeToString(s.Body)
+	for len(body) >= columns {
+		w.WriteString(body[:columns] + "\n")
+		body = body[columns:]
+	}
+	w.WriteString(body + "\n")
+}
+
+// header represents age header
+type header struct {
+	stanzas []*stanza
+	mac     []byte
+}
+
+// encodeWithoutMAC encodes header up to and including footer prefix
+func (h *header) encodeWithoutMAC() []byte {
+	w := &bytes.Buffer{}
+	w.WriteString(intro)
+	for _, s := range h.stanzas {
+		s.encode(w)
+	}
+	w.WriteString(footerPrefix)
+	return w.Bytes()
+}
+
+func (h *header) encode() []byte {
+	ret := h.encodeWithoutMAC()
+	return append(ret, []byte(" "+b64.EncodeToString(h.mac)+"\n")...)
+}
+
+func (h *header) sign(fileKey []byte) error {
+	mac, err := headerMAC(fileKey, h.encodeWithoutMAC())
+	if err != nil {
+		return err
+	}
+	h.mac = mac
+	return nil
+}
+
+func (h *header) verify(fileKey []byte) error {
+	mac, err := headerMAC(fileKey, h.encodeWithoutMAC())
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(mac, h.mac) {
+		return fmt.Errorf("bad header MAC")
+	}
+	return nil
+}
+
+// unwrap returns file key unwrapped by any of supplied identities
+func (h *header) unwrap(identities []*Identity) ([]byte, error) {
+	for _, identity := range identities {
+		for _, s := range h.stanzas {
+			fileKey, err := identity.unwrap(s)
+			if errors.Is(err, errIncorrectIdentity) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err = h.verify(fileKey); err != nil {
+				return nil, err
+			}
+			return fileKey, nil
+		}
+	}
+	return nil, fmt.Errorf("no identity matched any of the recipients")
+}
+
+func headerMAC(fileKey, data []byte) ([]byte, error) {
+	key, err := derive(fileKey, nil, "header", sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+func derive(secret, salt []byte, info string, size int) ([]byte, error) {
+	ret := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// parseHeader parses age header, returns header and remaining payload
+func parseHeader(data []byte) (*header, []byte, error) {
+	if !bytes.HasPrefix(data, []byte(intro)) {
+		return nil, nil, fmt.Errorf("invalid age header: unexpected intro")
+	}
+	rest := data[len(intro):]
+	readLine := func() (string, error) {
+		index := bytes.IndexByte(rest, '\n')
+		if index == -1 {
+			return "", fmt.Errorf("invalid age header: unexpected end")
+		}
+		line := string(rest[:index])
+		rest = rest[index+1:]
+		return line, nil
+	}
+	h := &header{}
+	for {
+		line, err := readLine()
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.HasPrefix(line, footerPrefix+" ") {
+			if h.mac, err = b64.DecodeString(line[len(footerPrefix)+1:]); err != nil {
+				return nil, nil, fmt.Errorf("invalid age header MAC: %w", err)
+			}
+			break
+		}
+		if !strings.HasPrefix(line, stanzaPrefix) {
+			return nil, nil, fmt.Errorf("invalid age header line: %q", line)
+		}
+		args := strings.Split(line[len(stanzaPrefix):], " ")
+		s := &stanza{Type: args[0], Args: args[1:]}
+		for {
+			bodyLine, err := readLine()
+			if err != nil {
+				return nil, nil, err
+			}
+			decoded, err := b64.DecodeString(bodyLine)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid age stanza body: %w", err)
+			}
+			s.Body = append(s.Body, decoded...)
+			if len(bodyLine) < columns {
+				break
+			}
+		}
+		h.stanzas = append(h.stanzas, s)
+	}
+	if len(h.stanzas) == 0 {
+		return nil, nil, fmt.Errorf("invalid age header: no recipients")
+	}
+	return h, rest, nil
+}
+
+func newHeader(fileKey []byte, recipients []*Recipient) (*header, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients specified")
+	}
+	h := &header{}
+	for _, recipient := range recipients {
+		s, err := recipient.wrap(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		h.stanzas = append(h.stanzas, s)
+	}
+	return h, h.sign(fileKey)
+}
+
+// Encrypt encrypts data to supplied recipients in age v1 format
+func Encrypt(data []byte, recipients ...*Recipient) ([]byte, error) {
+	fileKey := make([]byte, fileKeySize)
+	if _, err := io.ReadFull(rand.Reader, fileKey); err != nil {
+		return nil, err
+	}
+	h, err := newHeader(fileKey, recipients)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	ret := append(h.encode(), nonce...)
+	return sealPayload(ret, fileKey, nonce, data)
+}
+
+// Decrypt decrypts age v1 data with any of supplied identities
+func Decrypt(data []byte, identities ...*Identity) ([]byte, error) {
+	h, rest, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := h.unwrap(identities)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < nonceSize {
+		return nil, fmt.Errorf("invalid age payload: missing nonce")
+	}
+	return openPayload(fileKey, rest[:nonceSize], rest[nonceSize:])
+}
+
+// Rewrap re-wraps file key for supplied recipients, payload is preserved as is
+func Rewrap(data []byte, identities []*Identity, recipients []*Recipient) ([]byte, error) {
+	h, rest, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := h.unwrap(identities)
+	if err != nil {
+		return nil, err
+	}
+	rewrapped, err := newHeader(fileKey, recipients)
+	if err != nil {
+		return nil, err
+	}
+	return append(rewrapped.encode(), rest...), nil
+}
+
+func sealPayload(dst, fileKey, nonce, data []byte) ([]byte, error) {
+	aead, err := payloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	counter := make([]byte, chacha20poly1305.NonceSize)
+	for {
+		size := len(data)
+		if size > chunkSize {
+			size = chunkSize
+		}
+		last := size == len(data)
+		if last {
+			counter[len(counter)-1] = 1
+		}
+		dst = aead.Seal(dst, counter, data[:size], nil)
+		data = data[size:]
+		if last {
+			return dst, nil
+		}
+		incrementCounter(counter)
+	}
+}
+
+func openPayload(fileKey, nonce, data []byte) ([]byte, error) {
+	aead, err := payloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	sealedChunk := chunkSize + aead.Overhead()
+	counter := make([]byte, chacha20poly1305.NonceSize)
+	var ret []byte
+	for {
+		size := len(data)
+		if size > sealedChunk {
+			size = sealedChunk
+		}
+		last := size == len(data)
+		if last {
+			counter[len(counter)-1] = 1
+		}
+		if ret, err = aead.Open(ret, counter, data[:size], nil); err != nil {
+			return nil, fmt.Errorf("failed to decrypt age payload chunk: %w", err)
+		}
+		data = data[size:]
+		if last {
+			return ret, nil
+		}
+		incrementCounter(counter)
+	}
+}
+
+func payloadAEAD(fileKey, nonce []byte) (cipher.AEAD, error) {
+	key, err := derive(fileKey, nonce, "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+// incrementCounter increments 11-byte big endian chunk counter
+func incrementCounter(counter []byte) {
+	for i := len(counter) - 2; i >= 0; i-- {
+		counter[i]++
+		if counter[i] != 0 {
+			break
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package age
+
+import "github.com/viant/scy/kms"
+
+func init() {
+	kms.Register(Scheme, &Cipher{})
+}
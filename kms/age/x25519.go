@@ -0,0 +1,169 @@
+package age
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"strings"
+)
+
+const (
+	recipientHRP  = "age"
+	identityHRP   = "AGE-SECRET-KEY-"
+	x25519Type    = "X25519"
+	x25519Label   = "age-encryption.org/v1/X25519"
+	fileKeySize   = 16
+	x25519KeySize = curve25519.PointSize
+)
+
+var b64 = base64.RawStdEncoding.Strict()
+
+// Recipient represents X25519 public key
+type Recipient struct {
+	publicKey []byte
+}
+
+// String returns age1 encoded recipient
+func (r *Recipient) String() string {
+	ret, _ := bech32Encode(recipientHRP, r.publicKey)
+	return ret
+}
+
+// wrap wraps file key into X25519 stanza
+func (r *Recipient) wrap(fileKey []byte) (*stanza, error) {
+	ephemeral := make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(rand.Reader, ephemeral); err != nil {
+		return nil, err
+	}
+	share, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := curve25519.X25519(ephemeral, r.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapKey, err := x25519WrapKey(shared, share, r.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	body := aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil)
+	return &stanza{Type: x25519Type, Args: []string{b64.EncodeToString(share)}, Body: body}, nil
+}
+
+// Identity represents X25519 private key
+type Identity struct {
+	secretKey []byte
+	recipient *Recipient
+}
+
+// String returns AGE-SECRET-KEY-1 encoded identity
+func (i *Identity) String() string {
+	ret, _ := bech32Encode(identityHRP, i.secretKey)
+	return ret
+}
+
+// Recipient returns identity public key
+func (i *Identity) Recipient() *Recipient {
+	return i.recipient
+}
+
+// unwrap unwraps file key from X25519 stanza
+func (i *Identity) unwrap(s *stanza) ([]byte, error) {
+	if s.Type != x25519Type {
+		return nil, errIncorrectIdentity
+	}
+	if len(s.Args) != 1 {
+		return nil, fmt.Errorf("invalid X25519 stanza arguments")
+	}
+	share, err := b64.DecodeString(s.Args[0])
+	if err != nil || len(share) != x25519KeySize {
+		return nil, fmt.Errorf("invalid X25519 stanza share")
+	}
+	if len(s.Body) != fileKeySize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("invalid X25519 stanza body")
+	}
+	shared, err := curve25519.X25519(i.secretKey, share)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 recipient: %w", err)
+	}
+	wrapKey, err := x25519WrapKey(shared, share, i.recipient.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), s.Body, nil)
+	if err != nil {
+		return nil, errIncorrectIdentity
+	}
+	return fileKey, nil
+}
+
+func x25519WrapKey(shared, share, publicKey []byte) ([]byte, error) {
+	salt := make([]byte, 0, len(share)+len(publicKey))
+	salt = append(append(salt, share...), publicKey...)
+	wrapKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(x25519Label)), wrapKey); err != nil {
+		return nil, err
+	}
+	return wrapKey, nil
+}
+
+// ParseRecipient parses age1 encoded recipient
+func ParseRecipient(value string) (*Recipient, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", value, err)
+	}
+	if hrp != recipientHRP {
+		return nil, fmt.Errorf("invalid recipient %q: unexpected type %q", value, hrp)
+	}
+	if len(data) != x25519KeySize {
+		return nil, fmt.Errorf("invalid recipient %q: invalid key length", value)
+	}
+	return &Recipient{publicKey: data}, nil
+}
+
+// ParseIdentity parses AGE-SECRET-KEY-1 encoded identity
+func ParseIdentity(value string) (*Identity, error) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	if hrp != strings.ToLower(identityHRP) {
+		return nil, fmt.Errorf("invalid identity: unexpected type %q", hrp)
+	}
+	return newIdentity(data)
+}
+
+// GenerateIdentity generates a new X25519 identity
+func GenerateIdentity() (*Identity, error) {
+	secretKey := make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(rand.Reader, secretKey); err != nil {
+		return nil, err
+	}
+	return newIdentity(secretKey)
+}
+
+func newIdentity(secretKey []byte) (*Identity, error) {
+	if len(secretKey) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("invalid identity: invalid key length")
+	}
+	publicKey, err := curve25519.X25519(secretKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{secretKey: secretKey, recipient: &Recipient{publicKey: publicKey}}, nil
+}
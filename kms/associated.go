@@ -0,0 +1,32 @@
+package kms
+
+import "context"
+
+type associatedDataKey struct{}
+
+// AssociatedDataBinder is implemented by ciphers authenticating context associated data (see WithAssociatedData),
+// ciphertext bound to associated data fails to decrypt with a different one
+type AssociatedDataBinder interface {
+	//BindsAssociatedData returns true if cipher authenticates associated data
+	BindsAssociatedData() bool
+}
+
+// WithAssociatedData returns context carrying associated data, i.e. resource URL or logical name, nil data removes binding
+func WithAssociatedData(ctx context.Context, data []byte) context.Context {
+	return context.WithValue(ctx, associatedDataKey{}, data)
+}
+
+// AssociatedData returns context associated data or nil
+func AssociatedData(ctx context.Context) []byte {
+	if ctx == nil {
+		return nil
+	}
+	data, _ := ctx.Value(associatedDataKey{}).([]byte)
+	return data
+}
+
+// BindsAssociatedData returns true if cipher authenticates associated data
+func BindsAssociatedData(cipher Cipher) bool {
+	binder, ok := cipher.(AssociatedDataBinder)
+	return ok && binder.BindsAssociatedData()
+}
@@ -0,0 +1,161 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/viant/scy"
+	sauth "github.com/viant/scy/auth/aws"
+	"github.com/viant/scy/cred"
+	skms "github.com/viant/scy/kms"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Scheme represents aws cipher scheme
+const Scheme = "aws"
+
+const encryptionContextKey = "scy:resource"
+
+// Cipher represents aws kms cipher
+type Cipher struct {
+	config  *aws.Config
+	mux     sync.Mutex
+	clients map[string]*kms.Client
+}
+
+// Algorithm returns cipher algorithm name
+func (c *Cipher) Algorithm() string {
+	return "aws-kms"
+}
+
+// Authenticated returns true, decryption verifies ciphertext integrity
+func (c *Cipher) Authenticated() bool {
+	return true
+}
+
+// BindsAssociatedData returns true, context associated data is sent as encryption context
+func (c *Cipher) BindsAssociatedData() bool {
+	return true
+}
+
+// encryptionContext returns KMS encryption context for context associated data
+func encryptionContext(ctx context.Context) map[string]string {
+	if data := skms.AssociatedData(ctx); len(data) > 0 {
+		return map[string]string{encryptionContextKey: string(data)}
+	}
+	return nil
+}
+
+// Encrypt encrypts data with supplied key
+func (c *Cipher) Encrypt(ctx context.Context, key *skms.Key, data []byte) ([]byte, error) {
+	region, keyID, err := parseKeyPath(key.Path)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.client(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.Encrypt(ctx, &kms.EncryptInput{KeyId: &keyID, Plaintext: data, EncryptionContext: encryptionContext(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt with key %v, %w", key.Path, err)
+	}
+	return output.CiphertextBlob, nil
+}
+
+// Decrypt decrypts data with supplied key
+func (c *Cipher) Decrypt(ctx context.Context, key *skms.Key, data []byte) ([]byte, error) {
+	region, keyID, err := parseKeyPath(key.Path)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.client(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.Decrypt(ctx, &kms.DecryptInput{KeyId: &keyID, CiphertextBlob: data, EncryptionContext: encryptionContext(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %v, %w", key.Path, err)
+	}
+	return output.Plaintext, nil
+}
+
+// client returns region client, the registered cipher loads default aws config on first use
+func (c *Cipher) client(ctx context.Context, region string) (*kms.Client, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.config == nil {
+		config, err := sauth.NewConfig(ctx, &cred.Aws{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create aws config: %w", err)
+		}
+		c.config = config
+	}
+	if region == "" {
+		region = c.config.Region
+	}
+	if client, ok := c.clients[region]; ok {
+		return client, nil
+	}
+	client := kms.NewFromConfig(*c.config, func(options *kms.Options) {
+		options.Region = region
+	})
+	if c.clients == nil {
+		c.clients = map[string]*kms.Client{}
+	}
+	c.clients[region] = client
+	return client, nil
+}
+
+// parseKeyPath parses /region/key/id, /region/alias/name or /arn:aws:kms:... key path
+func parseKeyPath(keyPath string) (string, string, error) {
+	keyPath = strings.Trim(keyPath, "/")
+	if strings.HasPrefix(keyPath, "arn:") {
+		parts := strings.Split(keyPath, ":")
+		if len(parts) < 6 {
+			return "", "", fmt.Errorf("invalid aws kms key arn: %v", keyPath)
+		}
+		return parts[3], keyPath, nil
+	}
+	index := strings.Index(keyPath, "/")
+	if index == -1 {
+		return "", "", fmt.Errorf("invalid aws kms key: %v, expected: aws://kms/region/key/id or aws://kms/region/alias/name", keyPath)
+	}
+	region, keyID := keyPath[:index], keyPath[index+1:]
+	if strings.HasPrefix(keyID, "key/") {
+		keyID = keyID[len("key/"):]
+	}
+	if keyID == "" {
+		return "", "", fmt.Errorf("aws kms key id was empty: %v", keyPath)
+	}
+	return region, keyID, nil
+}
+
+// New creates aws kms cipher
+func New(ctx context.Context, awsCred *cred.Aws) (*Cipher, error) {
+	if awsCred == nil {
+		awsCred = &cred.Aws{}
+	}
+	config, err := sauth.NewConfig(ctx, awsCred)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aws config: %w", err)
+	}
+	return &Cipher{config: config, clients: map[string]*kms.Client{}}, nil
+}
+
+// NewWithResource creates aws kms cipher with cred.Aws secret resource
+func NewWithResource(ctx context.Context, resource *scy.Resource) (*Cipher, error) {
+	resource.SetTarget(reflect.TypeOf(cred.Aws{}))
+	secret, err := scy.New().Load(ctx, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws credentials: %w", err)
+	}
+	awsCred, ok := secret.Target.(*cred.Aws)
+	if !ok {
+		return nil, fmt.Errorf("unsupported aws credentials type: %T", secret.Target)
+	}
+	return New(ctx, awsCred)
+}
@@ -0,0 +1,125 @@
+package aws_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/cred"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/aws"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// kmsStandIn emulates AWS KMS Encrypt/Decrypt JSON protocol
+func kmsStandIn(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		keyID, _ := request["KeyId"].(string)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			plaintext, _ := request["Plaintext"].(string)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"KeyId": keyID, "CiphertextBlob": []byte(keyID + "|" + plaintext)})
+		case "TrentService.Decrypt":
+			blob, _ := request["CiphertextBlob"].(string)
+			data := []byte{}
+			_ = json.Unmarshal([]byte(`"`+blob+`"`), &data)
+			parts := bytes.SplitN(data, []byte("|"), 2)
+			if len(parts) != 2 || string(parts[0]) != keyID {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"IncorrectKeyException","message":"key mismatch"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"KeyId":"` + keyID + `","Plaintext":"` + string(parts[1]) + `"}`))
+		default:
+			t.Errorf("unexpected target: %v", r.Header.Get("X-Amz-Target"))
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestCipher_Encrypt(t *testing.T) {
+	server := kmsStandIn(t)
+	defer server.Close()
+	ctx := context.Background()
+	cipher, err := aws.New(ctx, &cred.Aws{Endpoint: server.URL, Region: "us-west-2", SecretKey: cred.SecretKey{Key: "test", Secret: "test"}})
+	if !assert.Nil(t, err) {
+		return
+	}
+	kms.Register(aws.Scheme, cipher)
+
+	var testCases = []struct {
+		description string
+		key         string
+		input       string
+	}{
+		{
+			description: "key id",
+			key:         "aws://kms/us-west-2/key/1234abcd-12ab-34cd-56ef-1234567890ab",
+			input:       "secret sequence @123!@#",
+		},
+		{
+			description: "alias",
+			key:         "aws://kms/us-east-1/alias/my-key",
+			input:       "alias secret",
+		},
+		{
+			description: "arn",
+			key:         "aws://kms/arn:aws:kms:us-west-2:111122223333:key/1234abcd",
+			input:       "arn secret",
+		},
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.key)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		srv, err := kms.Lookup(key.Scheme)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		encrypted, err := srv.Encrypt(ctx, key, []byte(testCase.input))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		actual, err := srv.Decrypt(ctx, key, encrypted)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, testCase.input, string(actual), testCase.description)
+	}
+
+	key, _ := kms.NewKey("aws://kms/us-west-2")
+	_, err = cipher.Encrypt(ctx, key, []byte("x"))
+	assert.NotNil(t, err, "invalid key path")
+}
+
+func TestCipher_DefaultConfig(t *testing.T) {
+	server := kmsStandIn(t)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	ctx := context.Background()
+	cipher := &aws.Cipher{} //registered by package init, aws config is loaded on first use
+	key, err := kms.NewKey("aws://kms/us-west-2/alias/default-config")
+	if !assert.Nil(t, err) {
+		return
+	}
+	encrypted, err := cipher.Encrypt(ctx, key, []byte("default config secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	decrypted, err := cipher.Decrypt(ctx, key, encrypted)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "default config secret", string(decrypted))
+}
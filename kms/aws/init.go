@@ -0,0 +1,7 @@
+package aws
+
+import "github.com/viant/scy/kms"
+
+func init() {
+	kms.Register(Scheme, &Cipher{})
+}
@@ -1,12 +1,15 @@
 package blowfish
 
 import (
+	"bytes"
 	"context"
 	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
 	"fmt"
 	"github.com/viant/scy/kms"
 	"golang.org/x/crypto/blowfish"
+	"io"
 )
 
 // legalBlowfishKey returns a key ≤ 56 bytes.
@@ -20,7 +23,21 @@ func EnsureKey(src []byte) []byte {
 	return sum[:]             // ← 32-byte slice
 }
 
-const scheme = "blowfish"
+// Scheme represents blowfish cipher scheme
+const Scheme = "blowfish"
+
+const (
+	//LegacyFormat represents zero IV, zero padded format, decrypted data ends at the first zero byte
+	LegacyFormat = 1
+	//PaddedFormat represents binary safe format with random IV and PKCS#7 padding
+	PaddedFormat = 2
+)
+
+// FormatMagic represents magic bytes prefixing PaddedFormat payload
+var FormatMagic = []byte{'S', 'C', 'Y', 'B'}
+
+// derivedKeySize represents passphrase derived key size
+const derivedKeySize = 32
 
 var defaultKey = []byte{0x24, 0x66, 0xDD, 0x87, 0x8B, 0x96, 0x3C, 0x9D}
 
@@ -35,37 +52,56 @@ func blowfishCheckSizeAndPad(padded []byte) []byte {
 	return padded
 }
 
-// Cipher represents blowfish cipher
-type Cipher struct{}
+// Cipher represents blowfish cipher, Format controls encryption format, both formats are decrypted
+type Cipher struct {
+	Format int
+}
+
+// Algorithm returns cipher algorithm name
+func (b *Cipher) Algorithm() string {
+	if b.Format == PaddedFormat {
+		return "blowfish-cbc-pkcs7"
+	}
+	return "blowfish-cbc"
+}
 
 // Encrypt encrypts data with supplied key
 func (b *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
-	cipherKey, err := key.Key(ctx, defaultKey)
+	cipherKey, prefix, err := key.EncryptionKey(ctx, defaultKey, derivedKeySize)
 	if err != nil {
 		return nil, err
 	}
-	blowfishCipher, err := blowfish.NewCipher(cipherKey)
+	blowfishCipher, err := newCipher(key, cipherKey)
 	if err != nil {
 		return nil, err
 	}
+	if b.Format == PaddedFormat {
+		return encryptPadded(blowfishCipher, prefix, data)
+	}
 	paddedSource := blowfishCheckSizeAndPad(data)
 	ciphertext := make([]byte, blowfish.BlockSize+len(paddedSource))
 	eiv := ciphertext[:blowfish.BlockSize]
 	encodedBlackEncryptor := cipher.NewCBCEncrypter(blowfishCipher, eiv)
 	encodedBlackEncryptor.CryptBlocks(ciphertext[blowfish.BlockSize:], paddedSource)
-	return ciphertext, nil
+	return append(prefix, ciphertext...), nil
 }
 
 // Decrypt decrypts data with supplied key
 func (b *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
-	cipherKey, err := key.Key(ctx, defaultKey)
+	cipherKey, data, err := key.DecryptionKey(ctx, defaultKey, data, derivedKeySize)
 	if err != nil {
 		return nil, err
 	}
-	blowfishCipher, err := blowfish.NewCipher(cipherKey)
+	blowfishCipher, err := newCipher(key, cipherKey)
 	if err != nil {
 		return nil, err
 	}
+	if bytes.HasPrefix(data, FormatMagic) {
+		return decryptPadded(blowfishCipher, data)
+	}
+	if len(data) < blowfish.BlockSize {
+		return nil, fmt.Errorf("invalid ciphertext length: %v", len(data))
+	}
 	div := data[:blowfish.BlockSize]
 	decrypted := data[blowfish.BlockSize:]
 	if len(decrypted)%blowfish.BlockSize != 0 {
@@ -82,3 +118,64 @@ func (b *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte
 	}
 	return result, nil
 }
+
+// encryptPadded encrypts data as magic, version, random IV and PKCS#7 padded CBC ciphertext
+func encryptPadded(blowfishCipher *blowfish.Cipher, prefix, data []byte) ([]byte, error) {
+	padLen := blowfish.BlockSize - len(data)%blowfish.BlockSize
+	padded := make([]byte, len(data), len(data)+padLen)
+	copy(padded, data)
+	padded = append(padded, bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+	offset := len(prefix) + len(FormatMagic) + 1
+	result := make([]byte, offset+blowfish.BlockSize+len(padded))
+	copy(result, prefix)
+	copy(result[len(prefix):], FormatMagic)
+	result[offset-1] = PaddedFormat
+	iv := result[offset : offset+blowfish.BlockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, fmt.Errorf("failed to generate iv: %w", err)
+	}
+	cipher.NewCBCEncrypter(blowfishCipher, iv).CryptBlocks(result[offset+blowfish.BlockSize:], padded)
+	return result, nil
+}
+
+func decryptPadded(blowfishCipher *blowfish.Cipher, data []byte) ([]byte, error) {
+	offset := len(FormatMagic) + 1
+	if len(data) < offset {
+		return nil, fmt.Errorf("invalid ciphertext length: %v", len(data))
+	}
+	if version := data[offset-1]; version != PaddedFormat {
+		return nil, fmt.Errorf("unsupported blowfish format: %v", version)
+	}
+	data = data[offset:]
+	if len(data) < 2*blowfish.BlockSize || len(data)%blowfish.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid ciphertext length: %v", len(data))
+	}
+	iv, decrypted := data[:blowfish.BlockSize], make([]byte, len(data)-blowfish.BlockSize)
+	cipher.NewCBCDecrypter(blowfishCipher, iv).CryptBlocks(decrypted, data[blowfish.BlockSize:])
+	padLen := int(decrypted[len(decrypted)-1])
+	if padLen == 0 || padLen > blowfish.BlockSize {
+		return nil, fmt.Errorf("invalid padding, wrong key or corrupted data")
+	}
+	for _, b := range decrypted[len(decrypted)-padLen:] {
+		if int(b) != padLen {
+			return nil, fmt.Errorf("invalid padding, wrong key or corrupted data")
+		}
+	}
+	return decrypted[:len(decrypted)-padLen], nil
+}
+
+// newCipher returns blowfish block cipher, key schedule is cached per key (see kms.CachedCipher)
+func newCipher(key *kms.Key, cipherKey []byte) (*blowfish.Cipher, error) {
+	block, err := kms.CachedCipher(key, Scheme, cipherKey, func(material []byte) (interface{}, error) {
+		return blowfish.NewCipher(material)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block.(*blowfish.Cipher), nil
+}
+
+// New creates blowfish cipher writing supplied format
+func New(format int) *Cipher {
+	return &Cipher{Format: format}
+}
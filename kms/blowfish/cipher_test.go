@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/assert"
 	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/blowfish"
 	"os"
 	"testing"
 )
@@ -38,6 +39,13 @@ func TestBlowfish_Encrypt(t *testing.T) {
 			envKey:      "myKey",
 			envValue:    "this is my key",
 		},
+		{
+			description: "passphrase key",
+			key:         "blowfish://pass/env/myPassphrase",
+			input:       "123456789!@#$%^&*",
+			envKey:      "myPassphrase",
+			envValue:    "correct horse battery staple",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -67,3 +75,54 @@ func TestBlowfish_Encrypt(t *testing.T) {
 	}
 
 }
+
+func TestCipher_PaddedFormat(t *testing.T) {
+	ctx := context.Background()
+	_ = os.Setenv("myBlowfishKey", "padded format key")
+	key, err := kms.NewKey("blowfish://env/myBlowfishKey")
+	if !assert.Nil(t, err) {
+		return
+	}
+	legacy := &blowfish.Cipher{}
+	padded := blowfish.New(blowfish.PaddedFormat)
+
+	var testCases = []struct {
+		description string
+		input       []byte
+	}{
+		{description: "empty", input: []byte{}},
+		{description: "text", input: []byte("secret sequence @123!@#")},
+		{description: "block aligned", input: []byte("12345678")},
+		{description: "binary with zero bytes", input: []byte{0x30, 0x82, 0x00, 0x00, 0x01, 0x00, 0xff, 0x00, 0x00}},
+	}
+	for _, testCase := range testCases {
+		encrypted, err := padded.Encrypt(ctx, key, testCase.input)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		again, _ := padded.Encrypt(ctx, key, testCase.input)
+		assert.NotEqualValues(t, encrypted, again, testCase.description+" random IV")
+		for _, decipher := range []*blowfish.Cipher{legacy, padded} {
+			actual, err := decipher.Decrypt(ctx, key, encrypted)
+			if assert.Nil(t, err, testCase.description) {
+				assert.EqualValues(t, testCase.input, actual, testCase.description)
+			}
+		}
+	}
+
+	legacyEncrypted, err := legacy.Encrypt(ctx, key, []byte("legacy secret"))
+	if assert.Nil(t, err) {
+		actual, err := padded.Decrypt(ctx, key, legacyEncrypted)
+		assert.Nil(t, err, "legacy payload")
+		assert.EqualValues(t, "legacy secret", string(actual), "legacy payload")
+	}
+
+	encrypted, _ := padded.Encrypt(ctx, key, []byte("secret"))
+	_, err = padded.Decrypt(ctx, key, encrypted[:len(encrypted)-1])
+	assert.NotNil(t, err, "truncated block")
+	encrypted[len(blowfish.FormatMagic)] = 3
+	_, err = padded.Decrypt(ctx, key, encrypted)
+	assert.NotNil(t, err, "unsupported version")
+	_, err = padded.Decrypt(ctx, key, blowfish.FormatMagic)
+	assert.NotNil(t, err, "truncated")
+}
@@ -3,5 +3,5 @@ package blowfish
 import "github.com/viant/scy/kms"
 
 func init() {
-	kms.Register(scheme, &Cipher{})
+	kms.Register(Scheme, &Cipher{Format: LegacyFormat})
 }
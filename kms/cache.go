@@ -0,0 +1,135 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultKeyCacheTTL represents default TTL of cached key material and ciphers
+const DefaultKeyCacheTTL = time.Minute
+
+var keyCache = newKeyCache(DefaultKeyCacheTTL)
+
+// SetKeyCacheTTL sets TTL of cached key material (downloaded key files, assembled shamir keys) and constructed ciphers,
+// zero or negative TTL disables caching
+func SetKeyCacheTTL(ttl time.Duration) {
+	keyCache.setTTL(ttl)
+}
+
+// InvalidateKeyCache removes cached key material and ciphers for supplied key URLs (Key.Raw), all entries without URLs
+func InvalidateKeyCache(keyURLs ...string) {
+	keyCache.invalidate(keyURLs...)
+}
+
+// CachedCipher returns cipher constructed by build for key material, cipher is cached by Key.Raw and name and reused
+// as long as key material does not change, cached ciphers have to be safe for concurrent use (i.e. cipher.Block, cipher.AEAD)
+func CachedCipher(key *Key, name string, material []byte, build func(material []byte) (interface{}, error)) (interface{}, error) {
+	if key.IsPassphrase() { //derived material changes with every salt
+		return build(material)
+	}
+	return keyCache.cipher(key.Raw, name, material, build)
+}
+
+type (
+	keyCacheEntry struct {
+		material []byte
+		expiry   time.Time
+		ciphers  map[string]*cipherCacheEntry
+	}
+
+	cipherCacheEntry struct {
+		material []byte
+		cipher   interface{}
+		expiry   time.Time
+	}
+
+	keyCacheStore struct {
+		mux     sync.Mutex
+		ttl     time.Duration
+		entries map[string]*keyCacheEntry
+	}
+)
+
+func (c *keyCacheStore) setTTL(ttl time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.ttl = ttl
+	c.entries = map[string]*keyCacheEntry{}
+}
+
+func (c *keyCacheStore) invalidate(keyURLs ...string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if len(keyURLs) == 0 {
+		c.entries = map[string]*keyCacheEntry{}
+		return
+	}
+	for _, keyURL := range keyURLs {
+		delete(c.entries, keyURL)
+	}
+}
+
+func (c *keyCacheStore) entry(keyURL string) *keyCacheEntry {
+	result, ok := c.entries[keyURL]
+	if !ok {
+		result = &keyCacheEntry{ciphers: map[string]*cipherCacheEntry{}}
+		c.entries[keyURL] = result
+	}
+	return result
+}
+
+// material returns cached key material or loads it, loaded material is cached unless TTL is disabled
+func (c *keyCacheStore) material(ctx context.Context, keyURL string, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	now := time.Now()
+	c.mux.Lock()
+	if c.ttl > 0 {
+		if entry, ok := c.entries[keyURL]; ok && entry.material != nil && now.Before(entry.expiry) {
+			material := entry.material
+			c.mux.Unlock()
+			return append([]byte{}, material...), nil
+		}
+	}
+	c.mux.Unlock()
+	material, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.ttl > 0 {
+		entry := c.entry(keyURL)
+		entry.material = append([]byte{}, material...)
+		entry.expiry = now.Add(c.ttl)
+	}
+	return material, nil
+}
+
+func (c *keyCacheStore) cipher(keyURL, name string, material []byte, build func(material []byte) (interface{}, error)) (interface{}, error) {
+	now := time.Now()
+	c.mux.Lock()
+	if c.ttl > 0 {
+		if entry, ok := c.entries[keyURL]; ok {
+			if cached, ok := entry.ciphers[name]; ok && now.Before(cached.expiry) && bytes.Equal(cached.material, material) {
+				c.mux.Unlock()
+				return cached.cipher, nil
+			}
+		}
+	}
+	c.mux.Unlock()
+	result, err := build(material)
+	if err != nil {
+		return nil, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.ttl > 0 {
+		c.entry(keyURL).ciphers[name] = &cipherCacheEntry{material: append([]byte{}, material...), cipher: result, expiry: now.Add(c.ttl)}
+	}
+	return result, nil
+}
+
+func newKeyCache(ttl time.Duration) *keyCacheStore {
+	return &keyCacheStore{ttl: ttl, entries: map[string]*keyCacheEntry{}}
+}
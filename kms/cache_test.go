@@ -0,0 +1,74 @@
+package kms_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestKey_Key_Cache(t *testing.T) {
+	defer kms.SetKeyCacheTTL(kms.DefaultKeyCacheTTL)
+	ctx := context.Background()
+	keyFile := path.Join(t.TempDir(), "cache.key")
+	assert.Nil(t, os.WriteFile(keyFile, []byte("first key material"), 0600))
+	key, err := kms.NewKey("aes://" + keyFile)
+	if !assert.Nil(t, err) {
+		return
+	}
+	material, err := key.Key(ctx, nil)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "first key material", material)
+
+	assert.Nil(t, os.WriteFile(keyFile, []byte("second key material"), 0600))
+	material, _ = key.Key(ctx, nil)
+	assert.EqualValues(t, "first key material", material, "cached")
+	kms.InvalidateKeyCache(key.Raw)
+	material, _ = key.Key(ctx, nil)
+	assert.EqualValues(t, "second key material", material, "invalidated")
+
+	kms.SetKeyCacheTTL(10 * time.Millisecond)
+	_, _ = key.Key(ctx, nil)
+	assert.Nil(t, os.WriteFile(keyFile, []byte("third key material"), 0600))
+	time.Sleep(20 * time.Millisecond)
+	material, _ = key.Key(ctx, nil)
+	assert.EqualValues(t, "third key material", material, "expired")
+
+	kms.SetKeyCacheTTL(0)
+	assert.Nil(t, os.WriteFile(keyFile, []byte("fourth key material"), 0600))
+	material, _ = key.Key(ctx, nil)
+	assert.EqualValues(t, "fourth key material", material, "disabled")
+}
+
+func TestCachedCipher(t *testing.T) {
+	defer kms.SetKeyCacheTTL(kms.DefaultKeyCacheTTL)
+	kms.SetKeyCacheTTL(time.Minute)
+	key, _ := kms.NewKey("aes://env/scyCachedCipherKey")
+	builds := 0
+	build := func(material []byte) (interface{}, error) {
+		builds++
+		return string(material), nil
+	}
+	for i := 0; i < 3; i++ {
+		cipher, err := kms.CachedCipher(key, "test", []byte("material"), build)
+		assert.Nil(t, err)
+		assert.EqualValues(t, "material", cipher)
+	}
+	assert.EqualValues(t, 1, builds)
+
+	cipher, _ := kms.CachedCipher(key, "test", []byte("rotated"), build)
+	assert.EqualValues(t, "rotated", cipher, "material changed")
+	assert.EqualValues(t, 2, builds)
+
+	kms.InvalidateKeyCache()
+	_, _ = kms.CachedCipher(key, "test", []byte("rotated"), build)
+	assert.EqualValues(t, 3, builds, "purged")
+
+	passphrase, _ := kms.NewKey("aes://pass/env/scyCachedCipherPassphrase")
+	_, _ = kms.CachedCipher(passphrase, "test", []byte("derived"), build)
+	_, _ = kms.CachedCipher(passphrase, "test", []byte("derived"), build)
+	assert.EqualValues(t, 5, builds, "passphrase keys are not cached")
+}
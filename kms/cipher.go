@@ -10,3 +10,29 @@ type Cipher interface {
 	//Encrypt encrypts data with supplied key
 	Encrypt(ctx context.Context, key *Key, data []byte) ([]byte, error)
 }
+
+// AlgorithmProvider is implemented by ciphers describing their algorithm in ciphertext header
+type AlgorithmProvider interface {
+	//Algorithm returns cipher algorithm name
+	Algorithm() string
+}
+
+// Authenticator is implemented by ciphers detecting a wrong key or tampered data on decrypt (i.e. AEAD)
+type Authenticator interface {
+	//Authenticated returns true if decryption verifies ciphertext integrity
+	Authenticated() bool
+}
+
+// Authenticates returns true if ciphers of the key (every keyring key) detect a wrong key or tampered data on decrypt
+func Authenticates(key *Key) bool {
+	for _, candidate := range key.Keys() {
+		cipher, err := Lookup(candidate.Scheme)
+		if err != nil {
+			return false
+		}
+		if authenticator, ok := cipher.(Authenticator); !ok || !authenticator.Authenticated() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,257 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// EnvelopeMagic represents magic bytes prefixing envelope encrypted payload
+var EnvelopeMagic = []byte{'S', 'C', 'Y', 'D'}
+
+const (
+	envelopeVersion    = 1
+	dataKeySize        = 32
+	defaultDataKeyTTL  = 5 * time.Minute
+	defaultDataKeySize = 1024
+)
+
+type (
+	// Envelope represents envelope cipher, payload is encrypted locally with AES-256-GCM data key, only the data key is wrapped with the underlying cipher
+	Envelope struct {
+		cipher    Cipher
+		ttl       time.Duration
+		maxSize   int
+		mux       sync.Mutex
+		unwrapped map[string]*dataKey
+		active    map[string]*dataKey
+		now       func() time.Time
+	}
+
+	dataKey struct {
+		plain   []byte
+		wrapped []byte
+		expiry  time.Time
+	}
+
+	// EnvelopeOption represents envelope option
+	EnvelopeOption func(e *Envelope)
+)
+
+// WithDataKeyTTL sets data key cache TTL, zero or negative TTL disables caching
+func WithDataKeyTTL(ttl time.Duration) EnvelopeOption {
+	return func(e *Envelope) {
+		e.ttl = ttl
+	}
+}
+
+// WithDataKeyCacheSize sets max number of cached unwrapped data keys
+func WithDataKeyCacheSize(size int) EnvelopeOption {
+	return func(e *Envelope) {
+		e.maxSize = size
+	}
+}
+
+// Algorithm returns cipher algorithm name
+func (e *Envelope) Algorithm() string {
+	wrapping := "unknown"
+	if provider, ok := e.cipher.(AlgorithmProvider); ok {
+		wrapping = provider.Algorithm()
+	}
+	return "envelope-aes-256-gcm+" + wrapping
+}
+
+// Authenticated returns true, envelope payload is sealed with AES-256-GCM
+func (e *Envelope) Authenticated() bool {
+	return true
+}
+
+// Encrypt encrypts data with a data key wrapped by supplied key
+func (e *Envelope) Encrypt(ctx context.Context, key *Key, data []byte) ([]byte, error) {
+	dek, err := e.activeKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dek.plain)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(EnvelopeMagic)+3+len(dek.wrapped)+len(nonce)+len(data)+aead.Overhead()))
+	buf.Write(EnvelopeMagic)
+	buf.WriteByte(envelopeVersion)
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(dek.wrapped)))
+	buf.Write(dek.wrapped)
+	buf.Write(nonce)
+	return aead.Seal(buf.Bytes(), nonce, data, envelopeAssociatedData(ctx, dek.wrapped)), nil
+}
+
+// Decrypt decrypts envelope payload, payload without envelope is decrypted with the underlying cipher
+func (e *Envelope) Decrypt(ctx context.Context, key *Key, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, EnvelopeMagic) {
+		return e.cipher.Decrypt(ctx, key, data)
+	}
+	offset := len(EnvelopeMagic)
+	if len(data) < offset+3 {
+		return nil, fmt.Errorf("invalid envelope: too short")
+	}
+	if version := data[offset]; version != envelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version: %v", version)
+	}
+	size := int(binary.BigEndian.Uint16(data[offset+1:]))
+	offset += 3
+	if len(data) < offset+size {
+		return nil, fmt.Errorf("invalid envelope: wrapped key was truncated")
+	}
+	wrapped := data[offset : offset+size]
+	offset += size
+	plainKey, err := e.unwrap(ctx, key, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(plainKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < offset+aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("invalid envelope: payload was truncated")
+	}
+	nonce := data[offset : offset+aead.NonceSize()]
+	result, err := aead.Open(nil, nonce, data[offset+aead.NonceSize():], envelopeAssociatedData(ctx, wrapped))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt envelope with key %v: %w", key.Name(), err)
+	}
+	return result, nil
+}
+
+// BindsAssociatedData returns true, context associated data is authenticated with the payload
+func (e *Envelope) BindsAssociatedData() bool {
+	return true
+}
+
+// envelopeAssociatedData returns wrapped data key followed by context associated data
+func envelopeAssociatedData(ctx context.Context, wrapped []byte) []byte {
+	associated := AssociatedData(ctx)
+	if len(associated) == 0 {
+		return wrapped
+	}
+	return append(append(make([]byte, 0, len(wrapped)+len(associated)), wrapped...), associated...)
+}
+
+// Purge removes all cached data keys
+func (e *Envelope) Purge() {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.unwrapped = map[string]*dataKey{}
+	e.active = map[string]*dataKey{}
+}
+
+func (e *Envelope) activeKey(ctx context.Context, key *Key) (*dataKey, error) {
+	now := e.now()
+	if e.ttl > 0 {
+		e.mux.Lock()
+		dek, ok := e.active[key.Raw]
+		e.mux.Unlock()
+		if ok && now.Before(dek.expiry) {
+			return dek, nil
+		}
+	}
+	plain := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, plain); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := e.cipher.Encrypt(WithAssociatedData(ctx, nil), key, plain) //cached data key is shared across resources
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key with %v: %w", key.Name(), err)
+	}
+	if len(wrapped) > 0xFFFF {
+		return nil, fmt.Errorf("wrapped data key too long: %v", len(wrapped))
+	}
+	dek := &dataKey{plain: plain, wrapped: wrapped, expiry: now.Add(e.ttl)}
+	if e.ttl > 0 {
+		e.mux.Lock()
+		e.active[key.Raw] = dek
+		e.put(key.Raw+string(wrapped), dek)
+		e.mux.Unlock()
+	}
+	return dek, nil
+}
+
+func (e *Envelope) unwrap(ctx context.Context, key *Key, wrapped []byte) ([]byte, error) {
+	cacheKey := key.Raw + string(wrapped)
+	now := e.now()
+	if e.ttl > 0 {
+		e.mux.Lock()
+		dek, ok := e.unwrapped[cacheKey]
+		e.mux.Unlock()
+		if ok && now.Before(dek.expiry) {
+			return dek.plain, nil
+		}
+	}
+	plain, err := e.cipher.Decrypt(WithAssociatedData(ctx, nil), key, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %v: %w", key.Name(), err)
+	}
+	if len(plain) != dataKeySize {
+		return nil, fmt.Errorf("invalid data key length: %v", len(plain))
+	}
+	if e.ttl > 0 {
+		e.mux.Lock()
+		e.put(cacheKey, &dataKey{plain: plain, wrapped: wrapped, expiry: now.Add(e.ttl)})
+		e.mux.Unlock()
+	}
+	return plain, nil
+}
+
+// put stores unwrapped key, expired or arbitrary entries are evicted when cache is full, caller has to hold lock
+func (e *Envelope) put(cacheKey string, dek *dataKey) {
+	if e.maxSize > 0 && len(e.unwrapped) >= e.maxSize {
+		now := e.now()
+		for k, v := range e.unwrapped {
+			if !now.Before(v.expiry) {
+				delete(e.unwrapped, k)
+			}
+		}
+		for k := range e.unwrapped {
+			if len(e.unwrapped) < e.maxSize {
+				break
+			}
+			delete(e.unwrapped, k)
+		}
+	}
+	e.unwrapped[cacheKey] = dek
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewEnvelope creates envelope cipher wrapping data keys with supplied cipher
+func NewEnvelope(cipher Cipher, opts ...EnvelopeOption) *Envelope {
+	ret := &Envelope{
+		cipher:    cipher,
+		ttl:       defaultDataKeyTTL,
+		maxSize:   defaultDataKeySize,
+		unwrapped: map[string]*dataKey{},
+		active:    map[string]*dataKey{},
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
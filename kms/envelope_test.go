@@ -0,0 +1,116 @@
+package kms_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	_ "github.com/viant/scy/kms/aes"
+	"testing"
+)
+
+type countingCipher struct {
+	kms.Cipher
+	encrypted int
+	decrypted int
+}
+
+func (c *countingCipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	c.encrypted++
+	return c.Cipher.Encrypt(ctx, key, data)
+}
+
+func (c *countingCipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	c.decrypted++
+	return c.Cipher.Decrypt(ctx, key, data)
+}
+
+func TestEnvelope_Encrypt(t *testing.T) {
+	ctx := context.Background()
+	key, err := kms.NewKey("aes://default")
+	if !assert.Nil(t, err) {
+		return
+	}
+	inner, _ := kms.Lookup(key.Scheme)
+	large := bytes.Repeat([]byte("-----BEGIN CERTIFICATE-----"), 100000)
+
+	var testCases = []struct {
+		description     string
+		options         []kms.EnvelopeOption
+		inputs          [][]byte
+		expectEncrypted int
+		expectDecrypted int
+	}{
+		{
+			description:     "cached data key",
+			inputs:          [][]byte{[]byte("secret 1"), []byte("secret 2"), large},
+			expectEncrypted: 1,
+			expectDecrypted: 0,
+		},
+		{
+			description:     "cache disabled",
+			options:         []kms.EnvelopeOption{kms.WithDataKeyTTL(0)},
+			inputs:          [][]byte{[]byte("secret 1"), []byte("secret 2")},
+			expectEncrypted: 2,
+			expectDecrypted: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		counter := &countingCipher{Cipher: inner}
+		envelope := kms.NewEnvelope(counter, testCase.options...)
+		for _, input := range testCase.inputs {
+			encrypted, err := envelope.Encrypt(ctx, key, input)
+			if !assert.Nil(t, err, testCase.description) {
+				continue
+			}
+			actual, err := envelope.Decrypt(ctx, key, encrypted)
+			if !assert.Nil(t, err, testCase.description) {
+				continue
+			}
+			assert.EqualValues(t, input, actual, testCase.description)
+		}
+		assert.EqualValues(t, testCase.expectEncrypted, counter.encrypted, testCase.description)
+		assert.EqualValues(t, testCase.expectDecrypted, counter.decrypted, testCase.description)
+	}
+}
+
+func TestEnvelope_Decrypt(t *testing.T) {
+	ctx := context.Background()
+	key, _ := kms.NewKey("aes://default")
+	inner, _ := kms.Lookup(key.Scheme)
+	envelope := kms.NewEnvelope(inner)
+
+	legacy, err := inner.Encrypt(ctx, key, []byte("legacy"))
+	assert.Nil(t, err)
+	actual, err := envelope.Decrypt(ctx, key, legacy)
+	assert.Nil(t, err, "legacy payload")
+	assert.EqualValues(t, "legacy", string(actual))
+
+	encrypted, err := envelope.Encrypt(ctx, key, []byte("secret"))
+	assert.Nil(t, err)
+	envelope.Purge()
+	actual, err = envelope.Decrypt(ctx, key, encrypted)
+	assert.Nil(t, err, "purged cache")
+	assert.EqualValues(t, "secret", string(actual))
+
+	encrypted[len(encrypted)-1] ^= 0x1
+	_, err = envelope.Decrypt(ctx, key, encrypted)
+	assert.NotNil(t, err, "tampered payload")
+}
+
+func TestEnvelope_Decrypt_InlineKey(t *testing.T) {
+	ctx := context.Background()
+	key, _ := kms.NewKey("aes://inline/envelopeInlineKey")
+	wrongKey, _ := kms.NewKey("aes://inline/wrongEnvelopeInlineKey")
+	inner, _ := kms.Lookup(key.Scheme)
+	envelope := kms.NewEnvelope(inner)
+	encrypted, err := envelope.Encrypt(ctx, key, []byte("secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = envelope.Decrypt(ctx, wrongKey, encrypted)
+	if assert.NotNil(t, err) {
+		assert.NotContains(t, err.Error(), "wrongEnvelopeInlineKey", "key material is not reported")
+	}
+}
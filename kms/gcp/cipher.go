@@ -17,11 +17,26 @@ type Cipher struct {
 	*cloudkms.Service
 }
 
+//Algorithm returns cipher algorithm name
+func (s *Cipher) Algorithm() string {
+	return "gcp-kms"
+}
+
+// Authenticated returns true, decryption verifies ciphertext integrity
+func (s *Cipher) Authenticated() bool {
+	return true
+}
+
+// BindsAssociatedData returns true, context associated data is sent as additional authenticated data
+func (s *Cipher) BindsAssociatedData() bool {
+	return true
+}
+
 //Encrypt encrypts plainText with supplied key
 func (s *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
 	service := cloudkms.NewProjectsLocationsKeyRingsCryptoKeysService(s.Service)
 	encoded := base64.StdEncoding.EncodeToString(data)
-	response, err := service.Encrypt(s.normalizeKeyPath(key.Path), &cloudkms.EncryptRequest{Plaintext: encoded}).Context(ctx).Do()
+	response, err := service.Encrypt(s.normalizeKeyPath(key.Path), &cloudkms.EncryptRequest{Plaintext: encoded, AdditionalAuthenticatedData: associatedData(ctx)}).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt with key %v, %w", key.Path, err)
 	}
@@ -31,7 +46,7 @@ func (s *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte
 //Decrypt decrypts plainText with supplied key
 func (s *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
 	service := cloudkms.NewProjectsLocationsKeyRingsCryptoKeysService(s.Service)
-	response, err := service.Decrypt(s.normalizeKeyPath(key.Path), &cloudkms.DecryptRequest{Ciphertext: string(data)}).Context(ctx).Do()
+	response, err := service.Decrypt(s.normalizeKeyPath(key.Path), &cloudkms.DecryptRequest{Ciphertext: string(data), AdditionalAuthenticatedData: associatedData(ctx)}).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt with key %v, %w", key.Path, err)
 	}
@@ -42,6 +57,13 @@ func (s *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte
 	return encoded, nil
 }
 
+func associatedData(ctx context.Context) string {
+	if data := kms.AssociatedData(ctx); len(data) > 0 {
+		return base64.StdEncoding.EncodeToString(data)
+	}
+	return ""
+}
+
 func (s *Cipher) normalizeKeyPath(keyPath string) string {
 	if keyPath[0] == '/' {
 		return keyPath[1:]
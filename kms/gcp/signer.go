@@ -0,0 +1,72 @@
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/viant/scy/kms"
+	"google.golang.org/api/cloudkms/v1"
+	"io"
+)
+
+// Signer represents crypto.Signer backed by cloud kms asymmetricSign, private key never leaves kms
+type Signer struct {
+	service   *cloudkms.ProjectsLocationsKeyRingsCryptoKeysCryptoKeyVersionsService
+	ctx       context.Context
+	name      string
+	algorithm string
+	publicKey crypto.PublicKey
+}
+
+// Public returns crypto key version public key
+func (s *Signer) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Algorithm returns crypto key version algorithm, i.e. RSA_SIGN_PKCS1_2048_SHA256
+func (s *Signer) Algorithm() string {
+	return s.algorithm
+}
+
+// Sign signs digest with crypto key version, rand is ignored
+func (s *Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	request := &cloudkms.AsymmetricSignRequest{Digest: &cloudkms.Digest{}}
+	encoded := base64.StdEncoding.EncodeToString(digest)
+	switch opts.HashFunc() {
+	case crypto.SHA256:
+		request.Digest.Sha256 = encoded
+	case crypto.SHA384:
+		request.Digest.Sha384 = encoded
+	case crypto.SHA512:
+		request.Digest.Sha512 = encoded
+	default:
+		return nil, fmt.Errorf("unsupported digest hash: %v", opts.HashFunc())
+	}
+	response, err := s.service.AsymmetricSign(s.name, request).Context(s.ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with key %v, %w", s.name, err)
+	}
+	return base64.StdEncoding.DecodeString(response.Signature)
+}
+
+// Signer returns signer for crypto key version, i.e. gcp://kms/projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1
+func (s *Cipher) Signer(ctx context.Context, key *kms.Key) (crypto.Signer, error) {
+	service := cloudkms.NewProjectsLocationsKeyRingsCryptoKeysCryptoKeyVersionsService(s.Service)
+	name := s.normalizeKeyPath(key.Path)
+	response, err := service.GetPublicKey(name).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key %v, %w", name, err)
+	}
+	block, _ := pem.Decode([]byte(response.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key %v PEM", name)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %v, %w", name, err)
+	}
+	return &Signer{service: service, ctx: context.WithoutCancel(ctx), name: name, algorithm: response.Algorithm, publicKey: publicKey}, nil
+}
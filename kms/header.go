@@ -0,0 +1,136 @@
+package kms
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// HeaderMagic represents magic bytes prefixing self-describing ciphertext
+var HeaderMagic = []byte{'S', 'C', 'Y', 'E'}
+
+// HeaderVersion represents current header format version
+const HeaderVersion = 1
+
+// Header represents self-describing ciphertext header
+type Header struct {
+	Version   int
+	Scheme    string
+	KeyID     string
+	Algorithm string
+	Binding   string //associated data binding kind, empty for unbound ciphertext
+}
+
+// Encode returns header followed by payload
+func (h *Header) Encode(payload []byte) ([]byte, error) {
+	fields := []string{h.Scheme, h.KeyID, h.Algorithm}
+	if h.Binding != "" {
+		fields = append(fields, h.Binding)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(HeaderMagic)+2+len(payload)+64))
+	buf.Write(HeaderMagic)
+	buf.WriteByte(byte(h.Version))
+	buf.WriteByte(byte(len(fields)))
+	for _, field := range fields {
+		if len(field) > 0xFFFF {
+			return nil, fmt.Errorf("header field too long: %v", len(field))
+		}
+		_ = binary.Write(buf, binary.BigEndian, uint16(len(field)))
+		buf.WriteString(field)
+	}
+	buf.Write(payload)
+	return buf.Bytes(), nil
+}
+
+// HasHeader returns true if data starts with header magic
+func HasHeader(data []byte) bool {
+	return bytes.HasPrefix(data, HeaderMagic)
+}
+
+// DecodeHeader decodes header, it returns nil header and unchanged data for legacy payload
+func DecodeHeader(data []byte) (*Header, []byte, error) {
+	if !HasHeader(data) {
+		return nil, data, nil
+	}
+	offset := len(HeaderMagic)
+	if len(data) < offset+2 {
+		return nil, nil, fmt.Errorf("invalid header: too short")
+	}
+	header := &Header{Version: int(data[offset])}
+	if header.Version < 1 || header.Version > HeaderVersion {
+		return nil, nil, fmt.Errorf("unsupported header version: %v", header.Version)
+	}
+	count := int(data[offset+1])
+	offset += 2
+	var fields []string
+	for i := 0; i < count; i++ {
+		if len(data) < offset+2 {
+			return nil, nil, fmt.Errorf("invalid header: field %v was truncated", i)
+		}
+		size := int(binary.BigEndian.Uint16(data[offset:]))
+		offset += 2
+		if len(data) < offset+size {
+			return nil, nil, fmt.Errorf("invalid header: field %v was truncated", i)
+		}
+		fields = append(fields, string(data[offset:offset+size]))
+		offset += size
+	}
+	if len(fields) < 3 {
+		return nil, nil, fmt.Errorf("invalid header: expected 3 fields but had %v", len(fields))
+	}
+	header.Scheme, header.KeyID, header.Algorithm = fields[0], fields[1], fields[2]
+	if len(fields) > 3 {
+		header.Binding = fields[3]
+	}
+	return header, data[offset:], nil
+}
+
+// DecodeHeaderReader decodes header from r, it returns nil header for legacy payload and a reader positioned after the header
+func DecodeHeaderReader(r io.Reader) (*Header, io.Reader, error) {
+	reader := bufio.NewReader(r)
+	if magic, _ := reader.Peek(len(HeaderMagic)); !HasHeader(magic) {
+		return nil, reader, nil
+	}
+	prefix := make([]byte, len(HeaderMagic)+2)
+	if _, err := io.ReadFull(reader, prefix); err != nil {
+		return nil, nil, fmt.Errorf("invalid header: too short")
+	}
+	data := prefix
+	for i := 0; i < int(prefix[len(prefix)-1]); i++ {
+		size := make([]byte, 2)
+		if _, err := io.ReadFull(reader, size); err != nil {
+			return nil, nil, fmt.Errorf("invalid header: field %v was truncated", i)
+		}
+		field := make([]byte, binary.BigEndian.Uint16(size))
+		if _, err := io.ReadFull(reader, field); err != nil {
+			return nil, nil, fmt.Errorf("invalid header: field %v was truncated", i)
+		}
+		data = append(append(data, size...), field...)
+	}
+	header, _, err := DecodeHeader(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, reader, nil
+}
+
+// NewHeader creates a header for supplied key and cipher, keyring is described by its primary key
+func NewHeader(key *Key, cipher Cipher) *Header {
+	if primary := key.Primary(); primary != key {
+		if primaryCipher, err := Lookup(primary.Scheme); err == nil {
+			key, cipher = primary, primaryCipher
+		}
+	}
+	algorithm := key.Scheme
+	if provider, ok := cipher.(AlgorithmProvider); ok {
+		algorithm = provider.Algorithm()
+	}
+	return &Header{
+		Version:   HeaderVersion,
+		Scheme:    key.Scheme,
+		KeyID:     key.ID(),
+		Algorithm: algorithm,
+	}
+}
@@ -0,0 +1,52 @@
+package kms_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	"testing"
+)
+
+func TestDecodeHeader(t *testing.T) {
+	var testCases = []struct {
+		description string
+		header      *kms.Header
+		payload     []byte
+	}{
+		{
+			description: "full header",
+			header:      &kms.Header{Version: kms.HeaderVersion, Scheme: "aes", KeyID: "aes://env/key", Algorithm: "aes-256-gcm"},
+			payload:     []byte{0x0, 0x1, 0x2},
+		},
+		{
+			description: "empty key id",
+			header:      &kms.Header{Version: kms.HeaderVersion, Scheme: "blowfish", Algorithm: "blowfish-cbc"},
+			payload:     []byte("abc"),
+		},
+		{
+			description: "associated data binding",
+			header:      &kms.Header{Version: kms.HeaderVersion, Scheme: "aes", KeyID: "aes://env/key", Algorithm: "aes-256-gcm", Binding: "url"},
+			payload:     []byte{0x3},
+		},
+	}
+	for _, testCase := range testCases {
+		encoded, err := testCase.header.Encode(testCase.payload)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		header, payload, err := kms.DecodeHeader(encoded)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, testCase.header, header, testCase.description)
+		assert.EqualValues(t, testCase.payload, payload, testCase.description)
+	}
+
+	legacy := []byte{0, 0, 0, 0, 0, 0, 0, 0, 1, 2}
+	header, payload, err := kms.DecodeHeader(legacy)
+	assert.Nil(t, err)
+	assert.Nil(t, header)
+	assert.EqualValues(t, legacy, payload)
+
+	_, _, err = kms.DecodeHeader(append(append([]byte{}, kms.HeaderMagic...), 1, 3, 0, 9))
+	assert.NotNil(t, err)
+}
@@ -11,14 +11,19 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // Key represents secret key
 type Key struct {
-	Raw    string
-	Path   string
-	Kind   string
-	Scheme string
+	Raw             string
+	Path            string
+	Kind            string
+	Scheme          string
+	passphraseValue []byte
+	keys            []*Key
+	matched         *Key
+	mux             sync.Mutex
 }
 
 // Key returns key data
@@ -32,6 +37,12 @@ func (k *Key) Key(ctx context.Context, defaultValue []byte) ([]byte, error) {
 		return defaultValue, nil
 	case "mac":
 		return getMacKey()
+	case PassphraseKind:
+		return nil, fmt.Errorf("passphrase key %v is not supported by %v cipher", k.Raw, k.Scheme)
+	case KeyringScheme:
+		return nil, fmt.Errorf("keyring key %v has no key data", k.Name())
+	case ShamirKind:
+		return keyCache.material(ctx, k.Raw, k.shamirKey)
 	case "env":
 		key := strings.Trim(k.Path, "/")
 		keyData := os.Getenv(key)
@@ -43,11 +54,33 @@ func (k *Key) Key(ctx context.Context, defaultValue []byte) ([]byte, error) {
 		}
 		return []byte(keyData), nil
 	default:
-		fs := afs.New()
-		return fs.DownloadWithURL(ctx, k.Path)
+		return keyCache.material(ctx, k.Raw, func(ctx context.Context) ([]byte, error) {
+			fs := afs.New()
+			return fs.DownloadWithURL(ctx, k.Path)
+		})
 	}
 }
 
+// ID returns key reference safe to be stored with ciphertext, key material kinds (raw, inline) return empty ID
+func (k *Key) ID() string {
+	if k.IsKeyring() {
+		return k.Primary().ID()
+	}
+	switch k.Kind {
+	case "raw", "inline":
+		return ""
+	}
+	return k.Raw
+}
+
+// Name returns key reference safe to be reported in errors and logs, key material kinds return scheme and kind only
+func (k *Key) Name() string {
+	if ID := k.ID(); ID != "" {
+		return ID
+	}
+	return k.Scheme + "://" + k.Kind
+}
+
 func getMacKey() ([]byte, error) {
 	macs, err := getHardwareAddresses()
 	if err != nil {
@@ -98,12 +131,20 @@ outer:
 	return macs, nil
 }
 
+// KeyScheme returns key scheme, i.e. aes for aes://env/KEY, gcp for projects/... KMS key names
+func KeyScheme(raw string) string {
+	if strings.HasPrefix(raw, "projects/") {
+		return "gcp"
+	}
+	return url.Scheme(raw, file.Scheme)
+}
+
 // NewKey creates a new key
 func NewKey(raw string) (*Key, error) {
-	scheme := url.Scheme(raw, file.Scheme)
+	scheme := KeyScheme(raw)
 	path := raw
-	if strings.HasPrefix(raw, "projects/") {
-		scheme = "gcp"
+	if scheme == KeyringScheme {
+		return newKeyring(raw)
 	}
 	_, err := kms.lookup(scheme)
 	if err != nil {
@@ -0,0 +1,147 @@
+package kms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// KeyringScheme represents keyring key scheme, i.e. keyring://aes://env/NEW_KEY,blowfish://default
+const KeyringScheme = "keyring"
+
+// Keyring represents keyring cipher, data is encrypted with the primary (first) key,
+// decryption tries each key until one succeeds
+type Keyring struct{}
+
+// Algorithm returns cipher algorithm name
+func (r *Keyring) Algorithm() string {
+	return KeyringScheme
+}
+
+// Encrypt encrypts data with keyring primary key
+func (r *Keyring) Encrypt(ctx context.Context, key *Key, data []byte) ([]byte, error) {
+	primary := key.Primary()
+	if primary == key {
+		return nil, fmt.Errorf("not a keyring key: %v", key.Name())
+	}
+	cipher, err := Lookup(primary.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	key.setMatched(primary)
+	return cipher.Encrypt(ctx, primary, data)
+}
+
+// Decrypt decrypts data with the first matching keyring key, keys with authenticated ciphers are tried first in keyring order,
+// keys with unauthenticated ciphers (i.e. blowfish) can not detect a wrong key, thus they are tried last
+func (r *Keyring) Decrypt(ctx context.Context, key *Key, data []byte) ([]byte, error) {
+	if len(key.keys) == 0 {
+		return nil, fmt.Errorf("not a keyring key: %v", key.Name())
+	}
+	var authenticated, unauthenticated []*Key
+	for _, candidate := range key.keys {
+		cipher, err := Lookup(candidate.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		if authenticator, ok := cipher.(Authenticator); ok && authenticator.Authenticated() {
+			authenticated = append(authenticated, candidate)
+		} else {
+			unauthenticated = append(unauthenticated, candidate)
+		}
+	}
+	var errors []string
+	for _, candidate := range append(authenticated, unauthenticated...) {
+		cipher, _ := Lookup(candidate.Scheme)
+		decrypted, err := cipher.Decrypt(ctx, candidate, append([]byte{}, data...))
+		if err == nil {
+			key.setMatched(candidate)
+			return decrypted, nil
+		}
+		errors = append(errors, fmt.Sprintf("%v: %v", candidate.ID(), err))
+	}
+	return nil, fmt.Errorf("failed to decrypt with any of %v keyring keys: %v", len(key.keys), strings.Join(errors, "; "))
+}
+
+// IsKeyring returns true if key is a keyring
+func (k *Key) IsKeyring() bool {
+	return len(k.keys) > 0
+}
+
+// Keys returns keyring keys, or the key itself for a non keyring key
+func (k *Key) Keys() []*Key {
+	if len(k.keys) == 0 {
+		return []*Key{k}
+	}
+	return k.keys
+}
+
+// HasID returns true if the key, or any keyring key, has supplied ID
+func (k *Key) HasID(ID string) bool {
+	for _, candidate := range k.Keys() {
+		if candidate.ID() == ID {
+			return true
+		}
+	}
+	return false
+}
+
+// Primary returns keyring primary key, or the key itself for a non keyring key
+func (k *Key) Primary() *Key {
+	if len(k.keys) == 0 {
+		return k
+	}
+	return k.keys[0]
+}
+
+// Matched returns keyring key that last encrypted or decrypted data, or the key itself for a non keyring key
+func (k *Key) Matched() *Key {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if k.matched == nil {
+		return k
+	}
+	return k.matched
+}
+
+func (k *Key) setMatched(key *Key) {
+	k.mux.Lock()
+	k.matched = key
+	k.mux.Unlock()
+}
+
+// newKeyring creates keyring key, keys are comma separated, an element without scheme is appended
+// to the previous one to support keys with comma in the path (i.e. age://inline/recipient1,recipient2)
+func newKeyring(raw string) (*Key, error) {
+	location := strings.TrimPrefix(raw, KeyringScheme+"://")
+	var elements []string
+	for _, element := range strings.Split(location, ",") {
+		if element = strings.TrimSpace(element); element == "" {
+			continue
+		}
+		if !strings.Contains(element, "://") && len(elements) > 0 {
+			elements[len(elements)-1] += "," + element
+			continue
+		}
+		elements = append(elements, element)
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("keyring was empty: %v", raw)
+	}
+	result := &Key{Raw: raw, Kind: KeyringScheme, Scheme: KeyringScheme, Path: location}
+	for _, element := range elements {
+		key, err := NewKey(element)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyring key %v: %w", element, err)
+		}
+		if key.IsKeyring() {
+			return nil, fmt.Errorf("nested keyring is not supported: %v", element)
+		}
+		result.keys = append(result.keys, key)
+	}
+	return result, nil
+}
+
+func init() {
+	Register(KeyringScheme, &Keyring{})
+}
@@ -0,0 +1,111 @@
+package kms_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	_ "github.com/viant/scy/kms/aes"
+	_ "github.com/viant/scy/kms/blowfish"
+	"os"
+	"testing"
+)
+
+func TestKeyring_Decrypt(t *testing.T) {
+	ctx := context.Background()
+	_ = os.Setenv("scyKeyringPrimary", "primary keyring key")
+	_ = os.Setenv("scyKeyringSecondary", "secondary keyring key")
+	keyring, err := kms.NewKey("keyring://aes://env/scyKeyringPrimary,aes://env/scyKeyringSecondary,blowfish://default")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, keyring.IsKeyring())
+	assert.Len(t, keyring.Keys(), 3)
+	assert.EqualValues(t, "aes://env/scyKeyringPrimary", keyring.ID())
+
+	var testCases = []struct {
+		description string
+		encryptKey  string
+		expectKey   string
+	}{
+		{description: "primary", encryptKey: "aes://env/scyKeyringPrimary", expectKey: "aes://env/scyKeyringPrimary"},
+		{description: "secondary", encryptKey: "aes://env/scyKeyringSecondary", expectKey: "aes://env/scyKeyringSecondary"},
+		{description: "unauthenticated legacy", encryptKey: "blowfish://default", expectKey: "blowfish://default"},
+		{description: "keyring", encryptKey: "keyring://aes://env/scyKeyringPrimary,blowfish://default", expectKey: "aes://env/scyKeyringPrimary"},
+	}
+	cipher, err := kms.Lookup(kms.KeyringScheme)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.encryptKey)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		keyCipher, _ := kms.Lookup(key.Scheme)
+		encrypted, err := keyCipher.Encrypt(ctx, key, []byte("keyring secret"))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		decrypted, err := cipher.Decrypt(ctx, keyring, encrypted)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, "keyring secret", string(decrypted), testCase.description)
+		assert.EqualValues(t, testCase.expectKey, keyring.Matched().Raw, testCase.description)
+	}
+}
+
+func TestKeyring_Encrypt(t *testing.T) {
+	ctx := context.Background()
+	_ = os.Setenv("scyKeyringPrimary", "primary keyring key")
+	keyring, err := kms.NewKey("keyring://aes://env/scyKeyringPrimary,blowfish://default")
+	if !assert.Nil(t, err) {
+		return
+	}
+	cipher, _ := kms.Lookup(keyring.Scheme)
+	encrypted, err := cipher.Encrypt(ctx, keyring, []byte("keyring secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	primary, _ := kms.NewKey("aes://env/scyKeyringPrimary")
+	primaryCipher, _ := kms.Lookup(primary.Scheme)
+	decrypted, err := primaryCipher.Decrypt(ctx, primary, encrypted)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "keyring secret", string(decrypted))
+
+	header := kms.NewHeader(keyring, cipher)
+	assert.EqualValues(t, &kms.Header{Version: kms.HeaderVersion, Scheme: "aes", KeyID: "aes://env/scyKeyringPrimary", Algorithm: "aes-256-gcm"}, header)
+
+	_, err = cipher.Decrypt(ctx, keyring, []byte("corrupted"))
+	assert.NotNil(t, err)
+}
+
+func TestNewKey_Keyring(t *testing.T) {
+	var testCases = []struct {
+		description string
+		raw         string
+		expect      []string
+		hasError    bool
+	}{
+		{description: "two keys", raw: "keyring://aes://default,blowfish://default", expect: []string{"aes://default", "blowfish://default"}},
+		{description: "comma in key path", raw: "keyring://aes://default, blowfish://env/a,b", expect: []string{"aes://default", "blowfish://env/a,b"}},
+		{description: "empty", raw: "keyring://", hasError: true},
+		{description: "unknown scheme", raw: "keyring://xyz://default", hasError: true},
+		{description: "nested", raw: "keyring://aes://default,keyring://aes://default", hasError: true},
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.raw)
+		if testCase.hasError {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		var actual []string
+		for _, item := range key.Keys() {
+			actual = append(actual, item.Raw)
+		}
+		assert.EqualValues(t, testCase.expect, actual, testCase.description)
+	}
+}
@@ -0,0 +1,243 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/viant/scy/kms"
+	"sync"
+	"time"
+)
+
+// Scheme represents memory cipher scheme, registered ciphers use a unique scheme with this prefix (see Register)
+const Scheme = "memory"
+
+// Op represents cipher operation
+type Op string
+
+const (
+	//Encrypt represents encrypt operation
+	Encrypt = Op("encrypt")
+	//Decrypt represents decrypt operation
+	Decrypt = Op("decrypt")
+)
+
+var magic = []byte{'S', 'C', 'Y', 'M'}
+
+const tagSize = 8
+
+// Call represents recorded cipher call
+type Call struct {
+	Op             Op
+	Key            string
+	Data           []byte
+	AssociatedData []byte
+	Err            error
+}
+
+type fault struct {
+	err     error
+	latency time.Duration
+}
+
+// Cipher represents in memory test cipher, ciphertext is a key stream derived from key URL (no key material is loaded),
+// tagged with key and associated data, so decryption with a different key or associated data fails
+type Cipher struct {
+	scheme     string
+	mux        sync.Mutex
+	calls      []*Call
+	faults     map[string]map[Op]*fault
+	plainTexts [][]byte
+}
+
+// Algorithm returns cipher algorithm name
+func (c *Cipher) Algorithm() string {
+	return "memory"
+}
+
+// Authenticated returns true, decryption verifies key and associated data tag
+func (c *Cipher) Authenticated() bool {
+	return true
+}
+
+// BindsAssociatedData returns true, context associated data is part of ciphertext tag
+func (c *Cipher) BindsAssociatedData() bool {
+	return true
+}
+
+// Scheme returns cipher scheme
+func (c *Cipher) Scheme() string {
+	return c.scheme
+}
+
+// Key returns key URL for supplied key name, i.e. memory1://name
+func (c *Cipher) Key(name string) string {
+	return c.scheme + "://" + name
+}
+
+// Encrypt encrypts data with supplied key
+func (c *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	if _, err := c.call(ctx, Encrypt, key, data); err != nil {
+		return nil, err
+	}
+	associatedData := kms.AssociatedData(ctx)
+	result := make([]byte, 0, len(magic)+tagSize+len(data))
+	result = append(result, magic...)
+	result = append(result, tag(key, associatedData, data)...)
+	return append(result, xor(key, data)...), nil
+}
+
+// Decrypt decrypts data with supplied key
+func (c *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	call, err := c.call(ctx, Decrypt, key, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(magic)+tagSize || !bytes.HasPrefix(data, magic) {
+		return nil, c.fail(call, fmt.Errorf("invalid memory ciphertext"))
+	}
+	plainText := xor(key, data[len(magic)+tagSize:])
+	if !hmac.Equal(data[len(magic):len(magic)+tagSize], tag(key, kms.AssociatedData(ctx), plainText)) {
+		return nil, c.fail(call, fmt.Errorf("failed to decrypt with key %v: tag mismatch", key.Raw))
+	}
+	return plainText, nil
+}
+
+// Fail scripts err for key URL operations, no operation matches both encrypt and decrypt, nil err removes failure
+func (c *Cipher) Fail(keyURL string, err error, ops ...Op) {
+	c.script(keyURL, ops, func(f *fault) { f.err = err })
+}
+
+// Delay scripts latency for key URL operations, no operation matches both encrypt and decrypt
+func (c *Cipher) Delay(keyURL string, latency time.Duration, ops ...Op) {
+	c.script(keyURL, ops, func(f *fault) { f.latency = latency })
+}
+
+// Calls returns recorded calls
+func (c *Cipher) Calls() []*Call {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	result := make([]*Call, len(c.calls))
+	for i, call := range c.calls {
+		clone := *call
+		result[i] = &clone
+	}
+	return result
+}
+
+// Count returns number of recorded op calls
+func (c *Cipher) Count(op Op) int {
+	count := 0
+	for _, call := range c.Calls() {
+		if call.Op == op {
+			count++
+		}
+	}
+	return count
+}
+
+// Reset removes recorded calls, plain texts and scripted faults
+func (c *Cipher) Reset() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.calls = nil
+	c.plainTexts = nil
+	c.faults = map[string]map[Op]*fault{}
+}
+
+// VerifyNoPlaintext returns error if data contains any plain text passed to Encrypt
+func (c *Cipher) VerifyNoPlaintext(data []byte) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, plainText := range c.plainTexts {
+		if len(plainText) > 0 && bytes.Contains(data, plainText) {
+			return fmt.Errorf("plain text %q was found in data", plainText)
+		}
+	}
+	return nil
+}
+
+func (c *Cipher) call(ctx context.Context, op Op, key *kms.Key, data []byte) (*Call, error) {
+	c.mux.Lock()
+	call := &Call{Op: op, Key: key.Raw, Data: append([]byte{}, data...), AssociatedData: kms.AssociatedData(ctx)}
+	c.calls = append(c.calls, call)
+	if op == Encrypt {
+		c.plainTexts = append(c.plainTexts, call.Data)
+	}
+	var scripted fault
+	if f, ok := c.faults[key.Raw][op]; ok {
+		scripted = *f
+	}
+	c.mux.Unlock()
+	if scripted.latency > 0 {
+		timer := time.NewTimer(scripted.latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			scripted.err = ctx.Err()
+		}
+	}
+	if scripted.err != nil {
+		return call, c.fail(call, scripted.err)
+	}
+	return call, nil
+}
+
+// fail records call error
+func (c *Cipher) fail(call *Call, err error) error {
+	c.mux.Lock()
+	call.Err = err
+	c.mux.Unlock()
+	return err
+}
+
+func (c *Cipher) script(keyURL string, ops []Op, apply func(f *fault)) {
+	if len(ops) == 0 {
+		ops = []Op{Encrypt, Decrypt}
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	byOp, ok := c.faults[keyURL]
+	if !ok {
+		byOp = map[Op]*fault{}
+		c.faults[keyURL] = byOp
+	}
+	for _, op := range ops {
+		if byOp[op] == nil {
+			byOp[op] = &fault{}
+		}
+		apply(byOp[op])
+	}
+}
+
+func tag(key *kms.Key, associatedData, plainText []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key.Raw))
+	length := make([]byte, 8)
+	binary.BigEndian.PutUint64(length, uint64(len(associatedData)))
+	mac.Write(length)
+	mac.Write(associatedData)
+	mac.Write(plainText)
+	return mac.Sum(nil)[:tagSize]
+}
+
+func xor(key *kms.Key, data []byte) []byte {
+	result := make([]byte, len(data))
+	var block []byte
+	for i := range data {
+		if i%sha256.Size == 0 {
+			sum := sha256.Sum256(append([]byte(key.Raw), byte(i/sha256.Size), byte(i/sha256.Size>>8)))
+			block = sum[:]
+		}
+		result[i] = data[i] ^ block[i%sha256.Size]
+	}
+	return result
+}
+
+// New creates memory cipher with supplied scheme, use Register to add it to kms registry
+func New(scheme string) *Cipher {
+	return &Cipher{scheme: scheme, faults: map[string]map[Op]*fault{}}
+}
@@ -0,0 +1,122 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/cred"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/memory"
+	"testing"
+	"time"
+)
+
+func TestCipher_Encrypt(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	registered, err := kms.Lookup(cipher.Scheme())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Same(t, cipher, registered)
+	key, _ := kms.NewKey(cipher.Key("app"))
+	otherKey, _ := kms.NewKey(cipher.Key("other"))
+
+	encrypted, err := cipher.Encrypt(ctx, key, []byte("top secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.NotContains(t, string(encrypted), "top secret")
+	assert.Nil(t, cipher.VerifyNoPlaintext(encrypted))
+	assert.NotNil(t, cipher.VerifyNoPlaintext([]byte(`{"Password":"top secret"}`)))
+	decrypted, err := cipher.Decrypt(ctx, key, encrypted)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "top secret", decrypted)
+	_, err = cipher.Decrypt(ctx, otherKey, encrypted)
+	assert.NotNil(t, err, "different key")
+	_, err = cipher.Decrypt(kms.WithAssociatedData(ctx, []byte("moved")), key, encrypted)
+	assert.NotNil(t, err, "different associated data")
+
+	calls := cipher.Calls()
+	if assert.Len(t, calls, 4) {
+		assert.EqualValues(t, memory.Encrypt, calls[0].Op)
+		assert.EqualValues(t, cipher.Key("app"), calls[0].Key)
+		assert.EqualValues(t, "top secret", calls[0].Data)
+		assert.NotNil(t, calls[2].Err)
+	}
+	assert.EqualValues(t, 3, cipher.Count(memory.Decrypt))
+}
+
+func TestCipher_Fail(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key, _ := kms.NewKey(cipher.Key("app"))
+	failure := errors.New("kms unavailable")
+	cipher.Fail(key.Raw, failure, memory.Decrypt)
+
+	encrypted, err := cipher.Encrypt(ctx, key, []byte("data"))
+	assert.Nil(t, err, "only decrypt was scripted")
+	_, err = cipher.Decrypt(ctx, key, encrypted)
+	assert.Same(t, failure, err)
+	cipher.Fail(key.Raw, nil)
+	_, err = cipher.Decrypt(ctx, key, encrypted)
+	assert.Nil(t, err, "failure removed")
+
+	cipher.Delay(key.Raw, 20*time.Millisecond)
+	started := time.Now()
+	_, err = cipher.Encrypt(ctx, key, []byte("data"))
+	assert.Nil(t, err)
+	assert.True(t, time.Since(started) >= 20*time.Millisecond)
+	cipher.Delay(key.Raw, time.Hour)
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = cipher.Encrypt(timeoutCtx, key, []byte("data"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	cipher.Reset()
+	assert.Empty(t, cipher.Calls())
+	_, err = cipher.Encrypt(ctx, key, []byte("data"))
+	assert.Nil(t, err, "reset removes faults")
+}
+
+func TestCipher_VerifySecurable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	basic := &cred.Basic{Username: "bob", Password: "distinctive-password"}
+	assert.Nil(t, cipher.VerifySecurable(ctx, basic, cipher.Key("app")))
+	assert.NotEmpty(t, basic.EncryptedPassword)
+	assert.NotNil(t, cipher.VerifySecurable(ctx, &leaky{Secret: "leaked-secret"}, cipher.Key("app")))
+}
+
+func TestRegister(t *testing.T) {
+	first, second := memory.Register(), memory.Register()
+	assert.NotEqual(t, first.Scheme(), second.Scheme())
+	first.Unregister()
+	_, err := kms.Lookup(first.Scheme())
+	assert.NotNil(t, err)
+	_, err = kms.Lookup(second.Scheme())
+	assert.Nil(t, err)
+	second.Unregister()
+}
+
+// leaky encrypts secret but keeps plain text
+type leaky struct {
+	Secret          string
+	EncryptedSecret []byte
+}
+
+func (l *leaky) Cipher(ctx context.Context, key *kms.Key) error {
+	cipher, err := kms.Lookup(key.Scheme)
+	if err != nil {
+		return err
+	}
+	l.EncryptedSecret, err = cipher.Encrypt(ctx, key, []byte(l.Secret))
+	return err
+}
+
+func (l *leaky) Decipher(ctx context.Context, key *kms.Key) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"fmt"
+	"github.com/viant/scy/kms"
+	"sync/atomic"
+)
+
+var sequence uint32
+
+// Cleaner is implemented by testing.TB
+type Cleaner interface {
+	Cleanup(func())
+}
+
+// Register creates cipher registered under a unique scheme (memory1, memory2...), so parallel tests never share
+// cipher state, Unregister has to be called once cipher is no longer needed
+func Register() *Cipher {
+	cipher := New(fmt.Sprintf("%v%v", Scheme, atomic.AddUint32(&sequence, 1)))
+	kms.Register(cipher.scheme, cipher)
+	return cipher
+}
+
+// RegisterCleanup registers cipher and unregisters it when test completes, i.e. memory.RegisterCleanup(t)
+func RegisterCleanup(t Cleaner) *Cipher {
+	cipher := Register()
+	t.Cleanup(cipher.Unregister)
+	return cipher
+}
+
+// Unregister removes cipher from kms registry
+func (c *Cipher) Unregister() {
+	kms.Unregister(c.scheme)
+}
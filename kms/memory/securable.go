@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/viant/scy/kms"
+)
+
+// VerifySecurable ciphers target with key URL and returns error if its JSON form still contains any encrypted plain text
+// or if target did not encrypt anything, use distinctive test values to avoid accidental matches
+func (c *Cipher) VerifySecurable(ctx context.Context, target kms.Securable, keyURL string) error {
+	key, err := kms.NewKey(keyURL)
+	if err != nil {
+		return err
+	}
+	encrypted := c.Count(Encrypt)
+	if err = target.Cipher(ctx, key); err != nil {
+		return err
+	}
+	if c.Count(Encrypt) == encrypted {
+		return fmt.Errorf("%T did not encrypt any data with %v", target, keyURL)
+	}
+	data, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	return c.VerifyNoPlaintext(data)
+}
@@ -0,0 +1,200 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/viant/scy/cred/secret/term"
+	"golang.org/x/crypto/argon2"
+	"io"
+	"os"
+	"strings"
+)
+
+// PassphraseKind represents passphrase derived key kind, i.e. aes://pass/env/MY_PASSPHRASE or aes://pass/term
+const PassphraseKind = "pass"
+
+// PassphraseMagic represents magic bytes prefixing passphrase KDF parameters stored with ciphertext
+var PassphraseMagic = []byte{'S', 'C', 'Y', 'P'}
+
+const (
+	passphraseVersion = 1
+	saltSize          = 16
+	minSaltSize       = 8
+	minKDFTime        = 1
+	maxKDFTime        = 64
+	minKDFMemory      = 8 * 1024    //KiB
+	maxKDFMemory      = 1024 * 1024 //KiB
+	minKDFThreads     = 1
+	maxKDFThreads     = 64
+)
+
+// KDFParams represents Argon2id key derivation parameters
+type KDFParams struct {
+	Time    uint32
+	Memory  uint32 //KiB
+	Threads uint8
+	Salt    []byte
+}
+
+// DefaultKDFParams represents default Argon2id parameters
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+func (p *KDFParams) encode() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, len(PassphraseMagic)+11+len(p.Salt)))
+	buf.Write(PassphraseMagic)
+	buf.WriteByte(passphraseVersion)
+	_ = binary.Write(buf, binary.BigEndian, p.Time)
+	_ = binary.Write(buf, binary.BigEndian, p.Memory)
+	buf.WriteByte(p.Threads)
+	buf.WriteByte(byte(len(p.Salt)))
+	buf.Write(p.Salt)
+	return buf.Bytes()
+}
+
+// Validate checks parameters are within bounds, stored parameters come with untrusted ciphertext
+func (p *KDFParams) Validate() error {
+	if p.Time < minKDFTime || p.Time > maxKDFTime {
+		return fmt.Errorf("invalid passphrase KDF time: %v, expected %v-%v", p.Time, minKDFTime, maxKDFTime)
+	}
+	if p.Memory < minKDFMemory || p.Memory > maxKDFMemory {
+		return fmt.Errorf("invalid passphrase KDF memory: %vKiB, expected %v-%vKiB", p.Memory, minKDFMemory, maxKDFMemory)
+	}
+	if p.Threads < minKDFThreads || p.Threads > maxKDFThreads {
+		return fmt.Errorf("invalid passphrase KDF threads: %v, expected %v-%v", p.Threads, minKDFThreads, maxKDFThreads)
+	}
+	if len(p.Salt) < minSaltSize {
+		return fmt.Errorf("invalid passphrase KDF salt size: %v, expected at least %v", len(p.Salt), minSaltSize)
+	}
+	return nil
+}
+
+func decodeKDFParams(data []byte) (*KDFParams, []byte, error) {
+	if !bytes.HasPrefix(data, PassphraseMagic) {
+		return nil, nil, fmt.Errorf("passphrase KDF parameters were missing")
+	}
+	offset := len(PassphraseMagic)
+	if len(data) < offset+11 {
+		return nil, nil, fmt.Errorf("invalid passphrase KDF parameters: too short")
+	}
+	if version := data[offset]; version != passphraseVersion {
+		return nil, nil, fmt.Errorf("unsupported passphrase KDF version: %v", version)
+	}
+	params := &KDFParams{
+		Time:    binary.BigEndian.Uint32(data[offset+1:]),
+		Memory:  binary.BigEndian.Uint32(data[offset+5:]),
+		Threads: data[offset+9],
+	}
+	saltLen := int(data[offset+10])
+	offset += 11
+	if len(data) < offset+saltLen {
+		return nil, nil, fmt.Errorf("invalid passphrase KDF parameters: salt was truncated")
+	}
+	params.Salt = data[offset : offset+saltLen]
+	if err := params.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return params, data[offset+saltLen:], nil
+}
+
+// IsPassphrase returns true if key is derived from passphrase
+func (k *Key) IsPassphrase() bool {
+	return k.Kind == PassphraseKind
+}
+
+// EncryptionKey returns key material and a prefix that has to be stored before ciphertext, only passphrase keys use prefix
+func (k *Key) EncryptionKey(ctx context.Context, defaultValue []byte, size int) ([]byte, []byte, error) {
+	if !k.IsPassphrase() {
+		material, err := k.Key(ctx, defaultValue)
+		return material, nil, err
+	}
+	params := DefaultKDFParams
+	params.Salt = make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, params.Salt); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	derived, err := k.derive(&params, size, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return derived, params.encode(), nil
+}
+
+// DecryptionKey returns key material and ciphertext without passphrase prefix
+func (k *Key) DecryptionKey(ctx context.Context, defaultValue []byte, data []byte, size int) ([]byte, []byte, error) {
+	if !k.IsPassphrase() {
+		material, err := k.Key(ctx, defaultValue)
+		return material, data, err
+	}
+	params, data, err := decodeKDFParams(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	derived, err := k.derive(params, size, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return derived, data, nil
+}
+
+// DecryptionKeyReader returns key material and reader positioned after passphrase prefix
+func (k *Key) DecryptionKeyReader(ctx context.Context, defaultValue []byte, r io.Reader, size int) ([]byte, io.Reader, error) {
+	if !k.IsPassphrase() {
+		material, err := k.Key(ctx, defaultValue)
+		return material, r, err
+	}
+	prefix := make([]byte, len(PassphraseMagic)+11)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, nil, fmt.Errorf("passphrase KDF parameters were missing: %w", err)
+	}
+	salt := make([]byte, int(prefix[len(prefix)-1]))
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, nil, fmt.Errorf("invalid passphrase KDF parameters: salt was truncated")
+	}
+	material, _, err := k.DecryptionKey(ctx, defaultValue, append(prefix, salt...), size)
+	return material, r, err
+}
+
+func (k *Key) derive(params *KDFParams, size int, confirm bool) ([]byte, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	passphrase, err := k.passphrase(confirm)
+	if err != nil {
+		return nil, err
+	}
+	return argon2.IDKey(passphrase, params.Salt, params.Time, params.Memory, params.Threads, uint32(size)), nil
+}
+
+// passphrase returns passphrase from env variable (pass/env/NAME) or terminal (pass/term), terminal passphrase is read once per key
+func (k *Key) passphrase(confirm bool) ([]byte, error) {
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	if len(k.passphraseValue) > 0 {
+		return k.passphraseValue, nil
+	}
+	source := strings.Trim(k.Path, "/")
+	switch {
+	case strings.HasPrefix(source, "env/"):
+		name := source[len("env/"):]
+		value := os.Getenv(name)
+		if value == "" {
+			return nil, fmt.Errorf("env.passphrase %v was empty", name)
+		}
+		return []byte(value), nil
+	case source == "term" || source == "":
+		value, err := term.ReadPassphrase(confirm, term.ReadingCredentialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			return nil, fmt.Errorf("passphrase was empty")
+		}
+		k.passphraseValue = []byte(value)
+		return k.passphraseValue, nil
+	default:
+		return nil, fmt.Errorf("unsupported passphrase source: %v, expected: env/NAME or term", source)
+	}
+}
@@ -0,0 +1,64 @@
+package kms_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	"os"
+	"testing"
+)
+
+func TestKey_DecryptionKey(t *testing.T) {
+	ctx := context.Background()
+	_ = os.Setenv("scyKDFPassphrase", "kdf passphrase")
+	key, err := kms.NewKey("aes://pass/env/scyKDFPassphrase")
+	if !assert.Nil(t, err) {
+		return
+	}
+	salt := bytes.Repeat([]byte{1}, 16)
+	var testCases = []struct {
+		description string
+		params      kms.KDFParams
+		expectErr   bool
+	}{
+		{description: "valid", params: kms.KDFParams{Time: 1, Memory: 8 * 1024, Threads: 1, Salt: salt}},
+		{description: "zero time", params: kms.KDFParams{Time: 0, Memory: 8 * 1024, Threads: 1, Salt: salt}, expectErr: true},
+		{description: "excessive time", params: kms.KDFParams{Time: 1 << 31, Memory: 8 * 1024, Threads: 1, Salt: salt}, expectErr: true},
+		{description: "zero memory", params: kms.KDFParams{Time: 1, Memory: 0, Threads: 1, Salt: salt}, expectErr: true},
+		{description: "excessive memory", params: kms.KDFParams{Time: 1, Memory: 1 << 31, Threads: 1, Salt: salt}, expectErr: true},
+		{description: "zero threads", params: kms.KDFParams{Time: 1, Memory: 8 * 1024, Threads: 0, Salt: salt}, expectErr: true},
+		{description: "excessive threads", params: kms.KDFParams{Time: 1, Memory: 8 * 1024, Threads: 255, Salt: salt}, expectErr: true},
+		{description: "short salt", params: kms.KDFParams{Time: 1, Memory: 8 * 1024, Threads: 1, Salt: salt[:2]}, expectErr: true},
+	}
+
+	for _, testCase := range testCases {
+		prefix := encodeKDFParams(&testCase.params)
+		data := append(prefix, []byte("ciphertext")...)
+		material, rest, err := key.DecryptionKey(ctx, nil, data, 32)
+		if testCase.expectErr {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.Len(t, material, 32, testCase.description)
+		assert.EqualValues(t, "ciphertext", string(rest), testCase.description)
+		_, _, err = key.DecryptionKeyReader(ctx, nil, bytes.NewReader(data), 32)
+		assert.Nil(t, err, testCase.description)
+	}
+}
+
+func encodeKDFParams(params *kms.KDFParams) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(kms.PassphraseMagic)
+	buf.WriteByte(1)
+	_ = binary.Write(buf, binary.BigEndian, params.Time)
+	_ = binary.Write(buf, binary.BigEndian, params.Memory)
+	buf.WriteByte(params.Threads)
+	buf.WriteByte(byte(len(params.Salt)))
+	buf.Write(params.Salt)
+	return buf.Bytes()
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/viant/scy/kms"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Scheme represents exec plugin scheme, i.e. exec://hsm/slot1/app runs scy-kms-hsm with /slot1/app key path
+const Scheme = "exec"
+
+// ExecutablePrefix represents plugin executable name prefix, plugin for scheme hsm is scy-kms-hsm
+const ExecutablePrefix = "scy-kms-"
+
+// DefaultTimeout represents default plugin call timeout
+const DefaultTimeout = 30 * time.Second
+
+// Cipher represents cipher delegating Encrypt and Decrypt to external executable, one process per call,
+// request is written to stdin and response read from stdout as JSON (see Request and Response)
+type Cipher struct {
+	//Executable represents plugin executable, empty for exec scheme resolving executable from key host
+	Executable string
+	Timeout    time.Duration
+}
+
+// Algorithm returns cipher algorithm name
+func (c *Cipher) Algorithm() string {
+	return Scheme
+}
+
+// Encrypt encrypts data with plugin
+func (c *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	return c.call(ctx, OpEncrypt, key, data)
+}
+
+// Decrypt decrypts data with plugin
+func (c *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	return c.call(ctx, OpDecrypt, key, data)
+}
+
+func (c *Cipher) call(ctx context.Context, operation string, key *kms.Key, data []byte) ([]byte, error) {
+	executable, err := c.executable(key)
+	if err != nil {
+		return nil, err
+	}
+	request, err := json.Marshal(&Request{Version: ProtocolVersion, Operation: operation, Key: key.Raw, Path: key.Path, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, executable)
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	command.Stdin, command.Stdout, command.Stderr = bytes.NewReader(request), stdout, stderr
+	if err = command.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("plugin %v failed to %v with key %v: %w: %v", executable, operation, key.Raw, err, message)
+		}
+		return nil, fmt.Errorf("plugin %v failed to %v with key %v: %w", executable, operation, key.Raw, err)
+	}
+	response := &Response{}
+	if err = json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("invalid plugin %v response: %w", executable, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %v failed to %v with key %v: %v", executable, operation, key.Raw, response.Error)
+	}
+	return response.Data, nil
+}
+
+// executable returns plugin executable, exec scheme uses key host as plugin name and the rest as key path
+func (c *Cipher) executable(key *kms.Key) (string, error) {
+	if c.Executable != "" {
+		return c.Executable, nil
+	}
+	if key.Kind == "" {
+		return "", fmt.Errorf("plugin name was empty, expected exec://name/key: %v", key.Raw)
+	}
+	return exec.LookPath(ExecutablePrefix + key.Kind)
+}
+
+// Discover returns plugin cipher for scheme if scy-kms-<scheme> executable is found on PATH
+func Discover(scheme string) (kms.Cipher, bool) {
+	if scheme == "" || strings.ContainsAny(scheme, `/\`) {
+		return nil, false
+	}
+	executable, err := exec.LookPath(ExecutablePrefix + scheme)
+	if err != nil {
+		return nil, false
+	}
+	return &Cipher{Executable: executable}, true
+}
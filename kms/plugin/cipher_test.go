@@ -0,0 +1,114 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/plugin"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const pluginEnv = "SCY_KMS_PLUGIN_TEST"
+
+var prefix = []byte("test:")
+
+// TestMain runs test binary as plugin when invoked by the plugin wrapper script
+func TestMain(m *testing.M) {
+	if os.Getenv(pluginEnv) != "" {
+		if err := plugin.Serve(handle); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func handle(ctx context.Context, request *plugin.Request) ([]byte, error) {
+	if strings.Contains(request.Path, "denied") {
+		return nil, fmt.Errorf("access denied: %v", request.Key)
+	}
+	switch request.Operation {
+	case plugin.OpEncrypt:
+		return append(append([]byte{}, prefix...), xor(request.Data)...), nil
+	case plugin.OpDecrypt:
+		if !bytes.HasPrefix(request.Data, prefix) {
+			return nil, fmt.Errorf("invalid ciphertext")
+		}
+		return xor(request.Data[len(prefix):]), nil
+	}
+	return nil, fmt.Errorf("unsupported operation: %v", request.Operation)
+}
+
+func xor(data []byte) []byte {
+	result := make([]byte, len(data))
+	for i := range data {
+		result[i] = data[i] ^ 0x5a
+	}
+	return result
+}
+
+// installPlugin creates scy-kms-<name> wrapper running the test binary as plugin and adds it to PATH
+func installPlugin(t *testing.T, name string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin wrapper script requires unix shell")
+	}
+	executable, err := os.Executable()
+	if !assert.Nil(t, err) {
+		return
+	}
+	dir := t.TempDir()
+	script := fmt.Sprintf("#!/bin/sh\n%v=1 exec %q\n", pluginEnv, executable)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, plugin.ExecutablePrefix+name), []byte(script), 0700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCipher_Encrypt(t *testing.T) {
+	installPlugin(t, "testhsm")
+	ctx := context.Background()
+	var testCases = []struct {
+		description string
+		key         string
+		hasError    bool
+	}{
+		{description: "exec scheme", key: "exec://testhsm/slot1/app"},
+		{description: "discovered scheme", key: "testhsm://slot1/app"},
+		{description: "plugin error", key: "exec://testhsm/slot1/denied", hasError: true},
+		{description: "missing plugin", key: "exec://missing/slot1/app", hasError: true},
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.key)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		cipher, err := kms.Lookup(key.Scheme)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		encrypted, err := cipher.Encrypt(ctx, key, []byte("plugin secret"))
+		if testCase.hasError {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.NotContains(t, string(encrypted), "plugin secret", testCase.description)
+		decrypted, err := cipher.Decrypt(ctx, key, encrypted)
+		assert.Nil(t, err, testCase.description)
+		assert.EqualValues(t, "plugin secret", decrypted, testCase.description)
+	}
+	_, err := kms.NewKey("unknownplugin://slot1/app")
+	assert.NotNil(t, err, "unknown scheme without plugin")
+}
+
+func TestServeIO(t *testing.T) {
+	output := new(bytes.Buffer)
+	assert.Nil(t, plugin.ServeIO(context.Background(), strings.NewReader(`{"version":2,"operation":"encrypt"}`), output, handle))
+	assert.Contains(t, output.String(), "unsupported protocol version")
+}
@@ -0,0 +1,8 @@
+package plugin
+
+import "github.com/viant/scy/kms"
+
+func init() {
+	kms.Register(Scheme, &Cipher{})
+	kms.RegisterDiscoverer(Discover)
+}
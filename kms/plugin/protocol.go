@@ -0,0 +1,26 @@
+package plugin
+
+// ProtocolVersion represents plugin protocol version
+const ProtocolVersion = 1
+
+const (
+	//OpEncrypt represents encrypt operation
+	OpEncrypt = "encrypt"
+	//OpDecrypt represents decrypt operation
+	OpDecrypt = "decrypt"
+)
+
+// Request represents plugin request written as a single JSON document to plugin stdin
+type Request struct {
+	Version   int    `json:"version"`
+	Operation string `json:"operation"`
+	Key       string `json:"key"`            //key URL, i.e. hsm://slot1/app or exec://hsm/slot1/app
+	Path      string `json:"path,omitempty"` //key path, i.e. /slot1/app
+	Data      []byte `json:"data"`           //base64 encoded plain text or ciphertext
+}
+
+// Response represents plugin response read as a single JSON document from plugin stdout
+type Response struct {
+	Data  []byte `json:"data,omitempty"` //base64 encoded ciphertext or plain text
+	Error string `json:"error,omitempty"`
+}
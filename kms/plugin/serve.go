@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Handler handles plugin request, returns ciphertext for encrypt and plain text for decrypt operation
+type Handler func(ctx context.Context, request *Request) ([]byte, error)
+
+// Serve handles a single request from stdin and writes response to stdout, use it in main of Go plugins
+func Serve(handler Handler) error {
+	return ServeIO(context.Background(), os.Stdin, os.Stdout, handler)
+}
+
+// ServeIO handles a single request from r and writes response to w, handler errors are reported as response error
+func ServeIO(ctx context.Context, r io.Reader, w io.Writer, handler Handler) error {
+	request := &Request{}
+	response := &Response{}
+	if err := json.NewDecoder(r).Decode(request); err != nil {
+		response.Error = fmt.Sprintf("invalid request: %v", err)
+	} else if request.Version != ProtocolVersion {
+		response.Error = fmt.Sprintf("unsupported protocol version: %v", request.Version)
+	} else if data, err := handler(ctx, request); err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Data = data
+	}
+	return json.NewEncoder(w).Encode(response)
+}
@@ -15,29 +15,82 @@ func Lookup(scheme string) (Cipher, error) {
 	return kms.lookup(scheme)
 }
 
+// Registered returns true if cipher was registered with supplied scheme, unlike Lookup it never runs discovery
+func Registered(scheme string) bool {
+	kms.mu.RLock()
+	defer kms.mu.RUnlock()
+	_, ok := kms.services[scheme]
+	return ok
+}
+
+// Discoverer returns cipher for a scheme that was not registered, i.e. external plugin executable
+type Discoverer func(scheme string) (Cipher, bool)
+
+// RegisterDiscoverer registers discoverer consulted by Lookup for unregistered schemes, discovered ciphers are registered,
+// schemes no discoverer resolved are not discovered again until another cipher or discoverer is registered
+func RegisterDiscoverer(discoverer Discoverer) {
+	kms.registerDiscoverer(discoverer)
+}
+
+// Unregister removes cipher registered with supplied scheme
+func Unregister(scheme string) {
+	kms.unregister(scheme)
+}
+
 var kms = newRegistry()
 
 type registry struct {
-	mu       sync.RWMutex
-	services map[string]Cipher
+	mu           sync.RWMutex
+	services     map[string]Cipher
+	discoverers  []Discoverer
+	undiscovered map[string]bool
 }
 
 func newRegistry() *registry {
-	return &registry{services: map[string]Cipher{}}
+	return &registry{services: map[string]Cipher{}, undiscovered: map[string]bool{}}
 }
 
 func (r *registry) register(scheme string, service Cipher) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.services[scheme] = service
+	delete(r.undiscovered, scheme)
+}
+
+func (r *registry) registerDiscoverer(discoverer Discoverer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.discoverers = append(r.discoverers, discoverer)
+	r.undiscovered = map[string]bool{}
+}
+
+func (r *registry) unregister(scheme string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.services, scheme)
 }
 
 func (r *registry) lookup(scheme string) (Cipher, error) {
 	r.mu.RLock()
 	srv, ok := r.services[scheme]
+	discoverers := r.discoverers
+	undiscovered := r.undiscovered[scheme]
 	r.mu.RUnlock()
-	if !ok {
-		return nil, fmt.Errorf("failed to lookup kms for: %v", scheme)
+	if ok {
+		return srv, nil
+	}
+	if !undiscovered {
+		for _, discover := range discoverers {
+			if srv, ok = discover(scheme); ok {
+				r.register(scheme, srv)
+				return srv, nil
+			}
+		}
+		r.mu.Lock()
+		if len(r.discoverers) == len(discoverers) { //discoverer registered meanwhile may resolve scheme
+			r.undiscovered[scheme] = true
+		}
+		r.mu.Unlock()
 	}
-	return srv, nil
+	return nil, fmt.Errorf("failed to lookup kms for: %v", scheme)
 }
@@ -0,0 +1,46 @@
+package kms_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/memory"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLookup_Discoverer(t *testing.T) {
+	const missing, found = "scyregistrymissing", "scyregistryfound"
+	defer kms.Unregister(found)
+	var calls = map[string]*int32{missing: new(int32), found: new(int32)}
+	kms.RegisterDiscoverer(func(scheme string) (kms.Cipher, bool) {
+		count, ok := calls[scheme]
+		if !ok {
+			return nil, false
+		}
+		atomic.AddInt32(count, 1)
+		if scheme == found {
+			return memory.New(scheme), true
+		}
+		return nil, false
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := kms.Lookup(missing)
+		assert.NotNil(t, err)
+		_, err = kms.Lookup(found)
+		assert.Nil(t, err)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(calls[missing]), "undiscovered scheme is cached")
+	assert.EqualValues(t, 1, atomic.LoadInt32(calls[found]), "discovered scheme is registered")
+	assert.False(t, kms.Registered(missing))
+
+	kms.RegisterDiscoverer(func(scheme string) (kms.Cipher, bool) { return nil, false })
+	_, err := kms.Lookup(missing)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(calls[missing]), "new discoverer resets undiscovered schemes")
+
+	kms.Register(missing, memory.New(missing))
+	defer kms.Unregister(missing)
+	_, err = kms.Lookup(missing)
+	assert.Nil(t, err)
+}
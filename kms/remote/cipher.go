@@ -0,0 +1,183 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/viant/scy/kms"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheme represents remote cipher scheme, i.e. remote://app uses app key held by kms daemon
+const Scheme = "remote"
+
+const (
+	//EndpointEnv represents env variable with default daemon endpoint, i.e. https://kms.internal:8443 or unix:///run/scy/kmsd.sock
+	EndpointEnv = "SCY_KMSD_URL"
+	//TokenEnv represents env variable with default caller JWT
+	TokenEnv = "SCY_KMSD_TOKEN"
+)
+
+const unixPrefix = "unix://"
+
+// TokenProvider returns caller JWT
+type TokenProvider func(ctx context.Context) (string, error)
+
+// Cipher represents cipher delegating Encrypt and Decrypt to kms daemon (see scy kmsd), key material never leaves the daemon
+type Cipher struct {
+	Endpoint string
+	Token    TokenProvider
+	Timeout  time.Duration
+	client   *http.Client
+	endpoint string
+	baseURL  string
+	info     *Response
+	mux      sync.Mutex
+}
+
+// Algorithm returns cipher algorithm name
+func (c *Cipher) Algorithm() string {
+	return Scheme
+}
+
+// Authenticated returns true if every daemon served key cipher verifies ciphertext integrity
+func (c *Cipher) Authenticated() bool {
+	info := c.capabilities()
+	return info != nil && info.Authenticated
+}
+
+// BindsAssociatedData returns true if every daemon served key cipher authenticates associated data
+func (c *Cipher) BindsAssociatedData() bool {
+	info := c.capabilities()
+	return info != nil && info.BindsAssociatedData
+}
+
+// Encrypt encrypts data with daemon key
+func (c *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	return c.call(ctx, EncryptPath, key, data)
+}
+
+// Decrypt decrypts data with daemon key
+func (c *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	return c.call(ctx, DecryptPath, key, data)
+}
+
+func (c *Cipher) call(ctx context.Context, path string, key *kms.Key, data []byte) ([]byte, error) {
+	name := strings.Trim(strings.TrimPrefix(key.Raw, Scheme+"://"), "/")
+	body, err := json.Marshal(&Request{Key: name, Data: data, AssociatedData: kms.AssociatedData(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.do(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// capabilities returns daemon capabilities, fetched once per endpoint, unreachable daemon reports no capabilities
+func (c *Cipher) capabilities() *Response {
+	if _, _, err := c.httpClient(); err != nil {
+		return nil
+	}
+	c.mux.Lock()
+	info, endpoint := c.info, c.endpoint
+	c.mux.Unlock()
+	if info != nil {
+		return info
+	}
+	info, err := c.do(context.Background(), http.MethodGet, InfoPath, nil)
+	if err != nil {
+		return nil
+	}
+	c.mux.Lock()
+	if c.endpoint == endpoint {
+		c.info = info
+	}
+	c.mux.Unlock()
+	return info
+}
+
+func (c *Cipher) do(ctx context.Context, method, path string, body []byte) (*Response, error) {
+	client, baseURL, err := c.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, method, baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	httpResponse, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call kms daemon: %w", err)
+	}
+	defer httpResponse.Body.Close()
+	response := &Response{}
+	if err = json.NewDecoder(httpResponse.Body).Decode(response); err != nil {
+		return nil, fmt.Errorf("invalid kms daemon response, status: %v, %w", httpResponse.StatusCode, err)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kms daemon error, status: %v, %v", httpResponse.StatusCode, response.Error)
+	}
+	return response, nil
+}
+
+func (c *Cipher) token(ctx context.Context) (string, error) {
+	if c.Token != nil {
+		return c.Token(ctx)
+	}
+	if token := os.Getenv(TokenEnv); token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("kms daemon token was empty, set %v", TokenEnv)
+}
+
+// httpClient returns client and base URL, unix socket endpoints use socket dialer
+func (c *Cipher) httpClient() (*http.Client, string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv(EndpointEnv)
+	}
+	if endpoint == "" {
+		return nil, "", fmt.Errorf("kms daemon endpoint was empty, set %v", EndpointEnv)
+	}
+	if c.client != nil && c.endpoint == endpoint {
+		return c.client, c.baseURL, nil
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	c.client = &http.Client{Timeout: timeout}
+	c.endpoint = endpoint
+	c.info = nil
+	c.baseURL = strings.TrimRight(endpoint, "/")
+	if strings.HasPrefix(endpoint, unixPrefix) {
+		socket := strings.TrimPrefix(endpoint, unixPrefix)
+		c.client.Transport = &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}}
+		c.baseURL = "http://unix"
+	}
+	return c.client, c.baseURL, nil
+}
+
+// New creates remote cipher for daemon endpoint, empty endpoint or token use SCY_KMSD_URL and SCY_KMSD_TOKEN env variables
+func New(endpoint string, token TokenProvider) *Cipher {
+	return &Cipher{Endpoint: endpoint, Token: token}
+}
@@ -0,0 +1,264 @@
+package remote_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	sjwt "github.com/viant/scy/auth/jwt"
+	"github.com/viant/scy/auth/jwt/signer"
+	"github.com/viant/scy/auth/jwt/verifier"
+	"github.com/viant/scy/kms"
+	_ "github.com/viant/scy/kms/aes"
+	_ "github.com/viant/scy/kms/blowfish"
+	"github.com/viant/scy/kms/remote"
+)
+
+type auditLog struct {
+	events []*remote.AuditEvent
+	mux    sync.Mutex
+}
+
+func (a *auditLog) add(event *remote.AuditEvent) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	a.events = append(a.events, event)
+}
+
+func newServer(t *testing.T) (*remote.Server, *auditLog, string) {
+	ctx := context.Background()
+	hmacKey := &scy.Resource{URL: filepath.Join(t.TempDir(), "hmac.key")}
+	assert.Nil(t, os.WriteFile(hmacKey.URL, []byte("kms daemon test hmac key"), 0600))
+	jwtVerifier := verifier.New(&verifier.Config{HMAC: hmacKey})
+	assert.Nil(t, jwtVerifier.Init(ctx))
+	jwtSigner := signer.New(&signer.Config{HMAC: hmacKey})
+	assert.Nil(t, jwtSigner.Init(ctx))
+	token, err := jwtSigner.Create(time.Hour, &sjwt.Claims{Email: "ci@example.com"})
+	assert.Nil(t, err)
+
+	t.Setenv("scyRemoteTestKey", "remote test key material")
+	keys := map[string]string{"app": "aes://env/scyRemoteTestKey", "restricted": "aes://env/scyRemoteTestKey"}
+	policies := map[string]*remote.Policy{"app": {Subjects: []string{"ci@example.com"}}, "restricted": {Audiences: []string{"ops"}}}
+	server, err := remote.NewServer(jwtVerifier, keys, policies)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	audit := &auditLog{}
+	server.Audit = audit.add
+	return server, audit, token
+}
+
+func TestCipher_Encrypt(t *testing.T) {
+	ctx := context.Background()
+	server, audit, token := newServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	tokenProvider := func(token string) remote.TokenProvider {
+		return func(ctx context.Context) (string, error) { return token, nil }
+	}
+
+	var testCases = []struct {
+		description string
+		cipher      *remote.Cipher
+		key         string
+		hasError    bool
+	}{
+		{description: "served key", cipher: remote.New(httpServer.URL, tokenProvider(token)), key: "remote://app"},
+		{description: "invalid token", cipher: remote.New(httpServer.URL, tokenProvider("invalid")), key: "remote://app", hasError: true},
+		{description: "unknown key", cipher: remote.New(httpServer.URL, tokenProvider(token)), key: "remote://other", hasError: true},
+		{description: "denied key", cipher: remote.New(httpServer.URL, tokenProvider(token)), key: "remote://restricted", hasError: true},
+		{description: "missing token", cipher: remote.New(httpServer.URL, tokenProvider("")), key: "remote://app", hasError: true},
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.key)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		encrypted, err := testCase.cipher.Encrypt(ctx, key, []byte("remote secret"))
+		if testCase.hasError {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		decrypted, err := testCase.cipher.Decrypt(ctx, key, encrypted)
+		assert.Nil(t, err, testCase.description)
+		assert.EqualValues(t, "remote secret", decrypted, testCase.description)
+
+		heldKey, _ := kms.NewKey("aes://env/scyRemoteTestKey")
+		heldCipher, _ := kms.Lookup(heldKey.Scheme)
+		decrypted, err = heldCipher.Decrypt(ctx, heldKey, encrypted)
+		assert.Nil(t, err, "encrypted with held key")
+		assert.EqualValues(t, "remote secret", decrypted, testCase.description)
+	}
+
+	if assert.Len(t, audit.events, 6, "rejected calls are audited") {
+		assert.EqualValues(t, "encrypt", audit.events[0].Operation)
+		assert.Empty(t, audit.events[0].Error)
+		assert.EqualValues(t, "decrypt", audit.events[1].Operation)
+		assert.EqualValues(t, "ci@example.com", audit.events[1].Subject)
+		assert.Contains(t, audit.events[2].Error, "unauthorized", "invalid token")
+		assert.Empty(t, audit.events[2].Subject)
+		assert.EqualValues(t, "other", audit.events[3].Key)
+		assert.NotEmpty(t, audit.events[3].Error)
+		assert.EqualValues(t, "restricted", audit.events[4].Key)
+		assert.EqualValues(t, "ci@example.com", audit.events[4].Subject)
+		assert.Contains(t, audit.events[4].Error, "denied")
+		assert.Contains(t, audit.events[5].Error, "missing")
+	}
+}
+
+func TestCipher_UnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket is not supported")
+	}
+	ctx := context.Background()
+	server, _, token := newServer(t)
+	socket := filepath.Join(t.TempDir(), "kmsd.sock")
+	listener, err := remote.Listen("unix://" + socket)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer listener.Close()
+	go func() { _ = http.Serve(listener, server) }()
+
+	t.Setenv(remote.EndpointEnv, "unix://"+socket)
+	t.Setenv(remote.TokenEnv, token)
+	key, err := kms.NewKey("remote://app")
+	if !assert.Nil(t, err) {
+		return
+	}
+	cipher, _ := kms.Lookup(remote.Scheme)
+	encrypted, err := cipher.Encrypt(ctx, key, []byte("socket secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	decrypted, err := cipher.Decrypt(ctx, key, encrypted)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "socket secret", decrypted)
+}
+
+func TestNewServer_Policy(t *testing.T) {
+	t.Setenv("scyRemoteTestKey", "remote test key material")
+	keys := map[string]string{"app": "aes://env/scyRemoteTestKey"}
+	var testCases = []struct {
+		description string
+		policies    map[string]*remote.Policy
+		hasError    bool
+	}{
+		{description: "subject policy", policies: map[string]*remote.Policy{"app": {Subjects: []string{remote.AnySubject}}}},
+		{description: "audience policy", policies: map[string]*remote.Policy{"app": {Audiences: []string{"ops"}}}},
+		{description: "missing policy", hasError: true},
+		{description: "empty policy", policies: map[string]*remote.Policy{"app": {}}, hasError: true},
+		{description: "policy of unknown key", policies: map[string]*remote.Policy{"app": {Subjects: []string{"ci"}}, "other": {Subjects: []string{"ci"}}}, hasError: true},
+	}
+	for _, testCase := range testCases {
+		_, err := remote.NewServer(&verifier.Service{}, keys, testCase.policies)
+		assert.EqualValues(t, testCase.hasError, err != nil, testCase.description)
+	}
+}
+
+func TestPolicy_Allows(t *testing.T) {
+	var testCases = []struct {
+		description string
+		policy      *remote.Policy
+		claims      *sjwt.Claims
+		expect      bool
+	}{
+		{description: "subject", policy: &remote.Policy{Subjects: []string{"ci"}}, claims: &sjwt.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "ci"}}, expect: true},
+		{description: "email", policy: &remote.Policy{Subjects: []string{"ci@example.com"}}, claims: &sjwt.Claims{Email: "ci@example.com"}, expect: true},
+		{description: "email with subject", policy: &remote.Policy{Subjects: []string{"ci@example.com"}}, claims: &sjwt.Claims{Email: "ci@example.com", RegisteredClaims: jwt.RegisteredClaims{Subject: "1234"}}, expect: true},
+		{description: "username with subject", policy: &remote.Policy{Subjects: []string{"ci"}}, claims: &sjwt.Claims{Username: "ci", RegisteredClaims: jwt.RegisteredClaims{Subject: "1234"}}, expect: true},
+		{description: "any subject", policy: &remote.Policy{Subjects: []string{remote.AnySubject}}, claims: &sjwt.Claims{}, expect: true},
+		{description: "audience", policy: &remote.Policy{Audiences: []string{"ops"}}, claims: &sjwt.Claims{RegisteredClaims: jwt.RegisteredClaims{Audience: []string{"dev", "ops"}}}, expect: true},
+		{description: "other subject", policy: &remote.Policy{Subjects: []string{"ci"}, Audiences: []string{"ops"}}, claims: &sjwt.Claims{Email: "dev@example.com", RegisteredClaims: jwt.RegisteredClaims{Audience: []string{"dev"}}}},
+		{description: "empty subject", policy: &remote.Policy{Subjects: []string{""}}, claims: &sjwt.Claims{}},
+		{description: "nil policy", claims: &sjwt.Claims{Email: "ci@example.com"}},
+	}
+	for _, testCase := range testCases {
+		assert.EqualValues(t, testCase.expect, testCase.policy.Allows(testCase.claims), testCase.description)
+	}
+}
+
+func TestCipher_AssociatedData(t *testing.T) {
+	ctx := context.Background()
+	server, audit, token := newServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	cipher := remote.New(httpServer.URL, func(ctx context.Context) (string, error) { return token, nil })
+	assert.True(t, cipher.Authenticated())
+	assert.True(t, kms.BindsAssociatedData(cipher))
+	if assert.Len(t, audit.events, 1, "capabilities are fetched once") {
+		assert.EqualValues(t, "info", audit.events[0].Operation)
+	}
+
+	key, _ := kms.NewKey("remote://app")
+	encrypted, err := cipher.Encrypt(kms.WithAssociatedData(ctx, []byte("file:///tmp/a.sec")), key, []byte("bound secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	decrypted, err := cipher.Decrypt(kms.WithAssociatedData(ctx, []byte("file:///tmp/a.sec")), key, encrypted)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "bound secret", decrypted)
+	_, err = cipher.Decrypt(kms.WithAssociatedData(ctx, []byte("file:///tmp/b.sec")), key, encrypted)
+	assert.NotNil(t, err, "different associated data")
+	_, err = cipher.Decrypt(ctx, key, encrypted)
+	assert.NotNil(t, err, "missing associated data")
+
+	heldKey, _ := kms.NewKey("aes://env/scyRemoteTestKey")
+	heldCipher, _ := kms.Lookup(heldKey.Scheme)
+	decrypted, err = heldCipher.Decrypt(kms.WithAssociatedData(ctx, []byte("file:///tmp/a.sec")), heldKey, encrypted)
+	assert.Nil(t, err, "associated data was forwarded to held key cipher")
+	assert.EqualValues(t, "bound secret", decrypted)
+}
+
+func TestCipher_Authenticated(t *testing.T) {
+	hmacKey := &scy.Resource{URL: filepath.Join(t.TempDir(), "hmac.key")}
+	assert.Nil(t, os.WriteFile(hmacKey.URL, []byte("kms daemon test hmac key"), 0600))
+	jwtVerifier := verifier.New(&verifier.Config{HMAC: hmacKey})
+	assert.Nil(t, jwtVerifier.Init(context.Background()))
+	jwtSigner := signer.New(&signer.Config{HMAC: hmacKey})
+	assert.Nil(t, jwtSigner.Init(context.Background()))
+	token, _ := jwtSigner.Create(time.Hour, &sjwt.Claims{Email: "ci@example.com"})
+	tokenProvider := func(ctx context.Context) (string, error) { return token, nil }
+	t.Setenv("scyRemoteTestKey", "remote test key material")
+
+	var testCases = []struct {
+		description string
+		keys        map[string]string
+		expect      bool
+	}{
+		{description: "authenticated ciphers", keys: map[string]string{"app": "aes://env/scyRemoteTestKey"}, expect: true},
+		{description: "unauthenticated cipher", keys: map[string]string{"app": "aes://env/scyRemoteTestKey", "legacy": "blowfish://default"}},
+	}
+	for _, testCase := range testCases {
+		policies := map[string]*remote.Policy{}
+		for name := range testCase.keys {
+			policies[name] = &remote.Policy{Subjects: []string{remote.AnySubject}}
+		}
+		server, err := remote.NewServer(jwtVerifier, testCase.keys, policies)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		server.Audit = func(event *remote.AuditEvent) {}
+		httpServer := httptest.NewServer(server)
+		cipher := remote.New(httpServer.URL, tokenProvider)
+		assert.EqualValues(t, testCase.expect, cipher.Authenticated(), testCase.description)
+		assert.EqualValues(t, testCase.expect, cipher.BindsAssociatedData(), testCase.description)
+		httpServer.Close()
+	}
+
+	unreachable := remote.New("http://127.0.0.1:1", tokenProvider)
+	assert.False(t, unreachable.Authenticated(), "unreachable daemon")
+	assert.False(t, unreachable.BindsAssociatedData(), "unreachable daemon")
+}
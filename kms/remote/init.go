@@ -0,0 +1,7 @@
+package remote
+
+import "github.com/viant/scy/kms"
+
+func init() {
+	kms.Register(Scheme, &Cipher{})
+}
@@ -0,0 +1,26 @@
+package remote
+
+const (
+	//EncryptPath represents daemon encrypt endpoint path
+	EncryptPath = "/v1/encrypt"
+	//DecryptPath represents daemon decrypt endpoint path
+	DecryptPath = "/v1/decrypt"
+	//InfoPath represents daemon cipher capabilities endpoint path
+	InfoPath = "/v1/info"
+)
+
+// Request represents daemon encrypt/decrypt request, caller JWT is passed as Authorization: Bearer header
+type Request struct {
+	Key            string `json:"key"`                      //served key name
+	Data           []byte `json:"data"`                     //base64 encoded plain text or ciphertext
+	AssociatedData []byte `json:"associatedData,omitempty"` //base64 encoded associated data (see kms.WithAssociatedData)
+}
+
+// Response represents daemon response
+type Response struct {
+	Data  []byte `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+	//Authenticated and BindsAssociatedData are returned by info endpoint, true only if every served key cipher has the capability
+	Authenticated       bool `json:"authenticated,omitempty"`
+	BindsAssociatedData bool `json:"bindsAssociatedData,omitempty"`
+}
@@ -0,0 +1,251 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	sjwt "github.com/viant/scy/auth/jwt"
+	"github.com/viant/scy/kms"
+)
+
+// MaxRequestSize represents max daemon request body size
+const MaxRequestSize = 32 * 1024 * 1024
+
+// Authenticator verifies caller JWT, verifier.Service implements it
+type Authenticator interface {
+	VerifyClaims(ctx context.Context, tokenString string) (*sjwt.Claims, error)
+}
+
+// AnySubject policy subject allows every authenticated caller
+const AnySubject = "*"
+
+// Policy represents served key access policy, a caller is allowed if any of its sub, email or username claims
+// or any of its audiences is listed
+type Policy struct {
+	Subjects  []string `json:",omitempty" yaml:"Subjects,omitempty"`
+	Audiences []string `json:",omitempty" yaml:"Audiences,omitempty"`
+}
+
+// Allows returns true if policy allows caller with supplied claims
+func (p *Policy) Allows(claims *sjwt.Claims) bool {
+	if p == nil || claims == nil {
+		return false
+	}
+	callers := []string{claims.Subject, claims.Email, claims.Username}
+	for _, candidate := range p.Subjects {
+		if candidate == AnySubject {
+			return true
+		}
+		for _, caller := range callers {
+			if caller != "" && candidate == caller {
+				return true
+			}
+		}
+	}
+	for _, audience := range claims.Audience {
+		for _, candidate := range p.Audiences {
+			if candidate == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AuditEvent represents audited daemon operation
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Key       string    `json:"key"`
+	Subject   string    `json:"subject,omitempty"`
+	Remote    string    `json:"remote,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type servedKey struct {
+	key    *kms.Key
+	cipher kms.Cipher
+	policy *Policy
+}
+
+// Server represents kms daemon serving Encrypt/Decrypt for held keys to JWT authenticated callers
+type Server struct {
+	authenticator Authenticator
+	keys          map[string]*servedKey
+	info          *Response
+	//Audit is called for every operation, including unauthenticated and rejected calls, defaults to JSON log line
+	Audit func(event *AuditEvent)
+}
+
+// ServeHTTP handles encrypt, decrypt and info requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var operation string
+	method := http.MethodPost
+	switch r.URL.Path {
+	case EncryptPath:
+		operation = "encrypt"
+	case DecryptPath:
+		operation = "decrypt"
+	case InfoPath:
+		operation = "info"
+		method = http.MethodGet
+	default:
+		writeResponse(w, http.StatusNotFound, &Response{Error: "not found"})
+		return
+	}
+	if r.Method != method {
+		writeResponse(w, http.StatusMethodNotAllowed, &Response{Error: "method not allowed"})
+		return
+	}
+	ctx := r.Context()
+	event := &AuditEvent{Time: time.Now().UTC(), Operation: operation, Remote: r.RemoteAddr}
+	tokenString := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(r.Header.Get("Authorization")), "Bearer"))
+	if tokenString == "" {
+		s.reject(w, event, http.StatusUnauthorized, "authorization token was missing", "authorization token was missing")
+		return
+	}
+	claims, err := s.authenticator.VerifyClaims(ctx, tokenString)
+	if err != nil {
+		s.reject(w, event, http.StatusUnauthorized, "unauthorized", fmt.Sprintf("unauthorized: %v", err))
+		return
+	}
+	event.Subject = subject(claims)
+	if operation == "info" {
+		s.Audit(event)
+		writeResponse(w, http.StatusOK, s.info)
+		return
+	}
+	request := &Request{}
+	if err = json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestSize)).Decode(request); err != nil {
+		message := fmt.Sprintf("invalid request: %v", err)
+		s.reject(w, event, http.StatusBadRequest, message, message)
+		return
+	}
+	event.Key = request.Key
+	status, response := s.handle(ctx, operation, request, claims)
+	event.Error = response.Error
+	s.Audit(event)
+	writeResponse(w, status, response)
+}
+
+// reject audits and writes rejected call response, audited reason can carry details not exposed to the caller
+func (s *Server) reject(w http.ResponseWriter, event *AuditEvent, status int, message, reason string) {
+	event.Error = reason
+	s.Audit(event)
+	writeResponse(w, status, &Response{Error: message})
+}
+
+func (s *Server) handle(ctx context.Context, operation string, request *Request, claims *sjwt.Claims) (int, *Response) {
+	served, ok := s.keys[request.Key]
+	if !ok {
+		return http.StatusNotFound, &Response{Error: fmt.Sprintf("key %v was not found", request.Key)}
+	}
+	if !served.policy.Allows(claims) {
+		return http.StatusForbidden, &Response{Error: fmt.Sprintf("access to key %v was denied", request.Key)}
+	}
+	if len(request.AssociatedData) > 0 {
+		ctx = kms.WithAssociatedData(ctx, request.AssociatedData)
+	}
+	var data []byte
+	var err error
+	if operation == "encrypt" {
+		data, err = served.cipher.Encrypt(ctx, served.key, request.Data)
+	} else {
+		data, err = served.cipher.Decrypt(ctx, served.key, request.Data)
+	}
+	if err != nil { //key material details are not exposed to callers
+		return http.StatusBadRequest, &Response{Error: fmt.Sprintf("failed to %v with key %v", operation, request.Key)}
+	}
+	return http.StatusOK, &Response{Data: data}
+}
+
+func subject(claims *sjwt.Claims) string {
+	switch {
+	case claims == nil:
+		return ""
+	case claims.Subject != "":
+		return claims.Subject
+	case claims.Email != "":
+		return claims.Email
+	}
+	return claims.Username
+}
+
+func writeResponse(w http.ResponseWriter, status int, response *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func logAudit(event *AuditEvent) {
+	data, _ := json.Marshal(event)
+	log.Printf("kmsd audit: %s", data)
+}
+
+// Listen returns listener for address, host:port or unix:///path/to/socket (created with 0660 permission)
+func Listen(address string) (net.Listener, error) {
+	if !strings.HasPrefix(address, unixPrefix) {
+		return net.Listen("tcp", address)
+	}
+	socket := strings.TrimPrefix(address, unixPrefix)
+	if _, err := os.Stat(socket); err == nil {
+		if err = os.Remove(socket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %v: %w", socket, err)
+		}
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(socket, 0660); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// NewServer creates kms daemon for served keys (name: key URL), i.e. {"app": "aes://env/APP_KEY"}, every served key requires
+// access policy (name: policy), i.e. {"app": {Subjects: []string{"ci@example.com"}}}
+func NewServer(authenticator Authenticator, keys map[string]string, policies map[string]*Policy) (*Server, error) {
+	if authenticator == nil {
+		return nil, fmt.Errorf("authenticator was empty")
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("served keys were empty")
+	}
+	result := &Server{authenticator: authenticator, keys: map[string]*servedKey{}, Audit: logAudit}
+	result.info = &Response{Authenticated: true, BindsAssociatedData: true}
+	for name, keyURL := range keys {
+		key, err := kms.NewKey(keyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %v: %w", name, err)
+		}
+		if key.Scheme == Scheme {
+			return nil, fmt.Errorf("invalid key %v: remote keys can not be served", name)
+		}
+		cipher, err := kms.Lookup(key.Scheme)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %v: %w", name, err)
+		}
+		policy := policies[name]
+		if policy == nil || len(policy.Subjects)+len(policy.Audiences) == 0 {
+			return nil, fmt.Errorf("invalid key %v: access policy was empty", name)
+		}
+		result.keys[name] = &servedKey{key: key, cipher: cipher, policy: policy}
+		result.info.Authenticated = result.info.Authenticated && kms.Authenticates(key)
+		result.info.BindsAssociatedData = result.info.BindsAssociatedData && kms.BindsAssociatedData(cipher)
+	}
+	for name := range policies {
+		if _, ok := keys[name]; !ok {
+			return nil, fmt.Errorf("invalid policy %v: key was not served", name)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package shamir
+
+// GF(2^8) arithmetic with the AES reducing polynomial x^8 + x^4 + x^3 + x + 1
+var (
+	expTable [510]byte
+	logTable [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		expTable[i+255] = x
+		logTable[x] = byte(i)
+		x = mulNoTable(x, 3)
+	}
+}
+
+func mulNoTable(a, b byte) byte {
+	var result byte
+	for b > 0 {
+		if b&1 == 1 {
+			result ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return result
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+// div divides a by b, b must not be zero
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+// evaluate evaluates polynomial with coefficients (constant term first) at x
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = mul(result, x) ^ coefficients[i]
+	}
+	return result
+}
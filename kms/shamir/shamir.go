@@ -0,0 +1,129 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	version    = 2
+	setIDSize  = 8
+	headerSize = 3 + setIDSize
+	//MaxShares represents max number of shares
+	MaxShares = 255
+)
+
+// Share represents a Shamir secret share, shares reveal nothing about the secret below threshold,
+// a wrong combination is detected when the combined key fails to decrypt authenticated (AEAD) ciphertext
+type Share struct {
+	Threshold int
+	X         byte
+	SetID     []byte //random share set ID detecting shares of different splits
+	Y         []byte
+}
+
+// Encode encodes share as base64 text
+func (s *Share) Encode() string {
+	data := make([]byte, 0, 3+len(s.SetID)+len(s.Y))
+	data = append(data, version, byte(s.Threshold), s.X)
+	data = append(data, s.SetID...)
+	data = append(data, s.Y...)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// Decode decodes base64 share text
+func Decode(text string) (*Share, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid share encoding: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("invalid share: too short")
+	}
+	if data[0] != version {
+		return nil, fmt.Errorf("unsupported share version: %v", data[0])
+	}
+	if len(data) <= headerSize {
+		return nil, fmt.Errorf("invalid share: too short")
+	}
+	share := &Share{Threshold: int(data[1]), X: data[2], SetID: data[3:headerSize], Y: data[headerSize:]}
+	if share.X == 0 || share.Threshold < 2 {
+		return nil, fmt.Errorf("invalid share: x: %v, threshold: %v", share.X, share.Threshold)
+	}
+	return share, nil
+}
+
+// Split splits secret into n shares, any threshold of them reconstructs the secret
+func Split(secret []byte, n, threshold int) ([]*Share, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret was empty")
+	}
+	if threshold < 2 || threshold > n || n > MaxShares {
+		return nil, fmt.Errorf("invalid shares: %v, threshold: %v, expected 2 <= threshold <= shares <= %v", n, threshold, MaxShares)
+	}
+	setID := make([]byte, setIDSize)
+	if _, err := rand.Read(setID); err != nil {
+		return nil, err
+	}
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{Threshold: threshold, X: byte(i + 1), SetID: setID, Y: make([]byte, len(secret))}
+	}
+	coefficients := make([]byte, threshold)
+	for i, value := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = value
+		for _, share := range shares {
+			share.Y[i] = evaluate(coefficients, share.X)
+		}
+	}
+	for i := range coefficients {
+		coefficients[i] = 0
+	}
+	return shares, nil
+}
+
+// Combine reconstructs secret from at least threshold shares of the same set, a tampered share is not detected here
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("shares were empty")
+	}
+	first := shares[0]
+	seen := map[byte]bool{}
+	var unique []*Share
+	for _, share := range shares {
+		if share.Threshold != first.Threshold || len(share.Y) != len(first.Y) || !bytes.Equal(share.SetID, first.SetID) {
+			return nil, fmt.Errorf("shares belong to different share sets")
+		}
+		if seen[share.X] {
+			continue
+		}
+		seen[share.X] = true
+		unique = append(unique, share)
+	}
+	if len(unique) < first.Threshold {
+		return nil, fmt.Errorf("not enough shares: expected %v but had %v", first.Threshold, len(unique))
+	}
+	unique = unique[:first.Threshold]
+	basis := make([]byte, len(unique))
+	for i, share := range unique {
+		basis[i] = 1
+		for j, other := range unique {
+			if i != j {
+				basis[i] = mul(basis[i], div(other.X, other.X^share.X))
+			}
+		}
+	}
+	secret := make([]byte, len(first.Y))
+	for k := range secret {
+		for i, share := range unique {
+			secret[k] ^= mul(share.Y[k], basis[i])
+		}
+	}
+	return secret, nil
+}
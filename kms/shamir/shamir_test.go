@@ -0,0 +1,93 @@
+package shamir_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms/shamir"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	secret := []byte("master key material \x00\x01\xff")
+	var testCases = []struct {
+		description string
+		n           int
+		threshold   int
+		use         []int
+		hasError    bool
+	}{
+		{description: "2 of 3", n: 3, threshold: 2, use: []int{0, 2}},
+		{description: "3 of 5", n: 5, threshold: 3, use: []int{4, 1, 3}},
+		{description: "all shares", n: 5, threshold: 3, use: []int{0, 1, 2, 3, 4}},
+		{description: "duplicate shares", n: 5, threshold: 3, use: []int{0, 0, 1}, hasError: true},
+		{description: "not enough shares", n: 5, threshold: 3, use: []int{0, 1}, hasError: true},
+		{description: "max shares", n: 255, threshold: 255, use: nil},
+	}
+	for _, testCase := range testCases {
+		shares, err := shamir.Split(secret, testCase.n, testCase.threshold)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.Len(t, shares, testCase.n, testCase.description)
+		var selected []*shamir.Share
+		for _, i := range testCase.use {
+			decoded, err := shamir.Decode(shares[i].Encode())
+			if !assert.Nil(t, err, testCase.description) {
+				continue
+			}
+			selected = append(selected, decoded)
+		}
+		if testCase.use == nil {
+			selected = shares
+		}
+		actual, err := shamir.Combine(selected)
+		if testCase.hasError {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		assert.Nil(t, err, testCase.description)
+		assert.EqualValues(t, secret, actual, testCase.description)
+	}
+}
+
+func TestSplit_InvalidParameters(t *testing.T) {
+	for _, params := range [][2]int{{3, 1}, {2, 3}, {256, 3}} {
+		_, err := shamir.Split([]byte("secret"), params[0], params[1])
+		assert.NotNil(t, err, params)
+	}
+	_, err := shamir.Split(nil, 3, 2)
+	assert.NotNil(t, err)
+}
+
+func TestCombine_Mismatch(t *testing.T) {
+	first, _ := shamir.Split([]byte("first secret"), 3, 2)
+	second, _ := shamir.Split([]byte("other secret"), 3, 2)
+	_, err := shamir.Combine([]*shamir.Share{first[0], second[1]})
+	assert.NotNil(t, err, "shares of different sets")
+	again, _ := shamir.Split([]byte("first secret"), 3, 2)
+	_, err = shamir.Combine([]*shamir.Share{first[0], again[1]})
+	assert.NotNil(t, err, "shares of different splits of the same secret")
+	assert.NotEqualValues(t, first[0].SetID, again[0].SetID)
+
+	first[1].Y[0] ^= 0xff
+	tampered, err := shamir.Combine([]*shamir.Share{first[0], first[1]})
+	assert.Nil(t, err, "tampered share is detected by authenticated use of the key")
+	assert.NotEqualValues(t, "first secret", string(tampered))
+
+	_, err = shamir.Decode("not a share")
+	assert.NotNil(t, err)
+}
+
+func TestShare_Encode(t *testing.T) {
+	secret := []byte("low entropy")
+	shares, err := shamir.Split(secret, 3, 2)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for _, share := range shares {
+		assert.Len(t, share.SetID, 8)
+		decoded, err := shamir.Decode(share.Encode())
+		if assert.Nil(t, err) {
+			assert.EqualValues(t, share, decoded)
+		}
+	}
+}
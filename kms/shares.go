@@ -0,0 +1,73 @@
+package kms
+
+import (
+	"context"
+	"fmt"
+	"github.com/viant/afs"
+	"github.com/viant/scy/kms/shamir"
+	"os"
+	"strings"
+)
+
+// ShamirKind represents a key kind assembled from Shamir shares at load time,
+// i.e. aes://shamir/~/.secret/share1,/mnt/usb/share2,gs://bucket/share3
+const ShamirKind = "shamir"
+
+// IsShamir returns true if key is assembled from Shamir shares
+func (k *Key) IsShamir() bool {
+	return k.Kind == ShamirKind
+}
+
+// ShareLocations returns Shamir share locations, locations without a scheme are local paths
+func (k *Key) ShareLocations() []string {
+	location := k.Raw
+	if index := strings.Index(location, "://"); index != -1 {
+		location = location[index+3:]
+	}
+	location = strings.TrimPrefix(strings.TrimPrefix(location, ShamirKind), "/")
+	var result []string
+	for _, element := range strings.Split(location, ",") {
+		if element = strings.TrimSpace(element); element == "" {
+			continue
+		}
+		switch {
+		case strings.Contains(element, "://"):
+		case strings.HasPrefix(element, "~"):
+			element = os.Getenv("HOME") + element[1:]
+		case !strings.HasPrefix(element, "/"):
+			element = "/" + element
+		}
+		result = append(result, element)
+	}
+	return result
+}
+
+// shamirKey assembles key from shares, unavailable shares are ignored as long as the threshold is met
+func (k *Key) shamirKey(ctx context.Context) ([]byte, error) {
+	locations := k.ShareLocations()
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("shamir share locations were empty: %v", k.Raw)
+	}
+	fs := afs.New()
+	var shares []*shamir.Share
+	var failures []string
+	for _, location := range locations {
+		data, err := fs.DownloadWithURL(ctx, location)
+		if err == nil {
+			var share *shamir.Share
+			if share, err = shamir.Decode(string(data)); err == nil {
+				shares = append(shares, share)
+				continue
+			}
+		}
+		failures = append(failures, fmt.Sprintf("%v: %v", location, err))
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("failed to load shamir shares: %v", strings.Join(failures, "; "))
+	}
+	key, err := shamir.Combine(shares)
+	if err != nil && len(failures) > 0 {
+		return nil, fmt.Errorf("%w, unavailable shares: %v", err, strings.Join(failures, "; "))
+	}
+	return key, err
+}
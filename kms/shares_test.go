@@ -0,0 +1,91 @@
+package kms_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	_ "github.com/viant/scy/kms/aes"
+	"github.com/viant/scy/kms/shamir"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestKey_Shamir(t *testing.T) {
+	ctx := context.Background()
+	baseDir := path.Join(os.TempDir(), "scy_shamir")
+	_ = os.MkdirAll(baseDir, 0700)
+	defer os.RemoveAll(baseDir)
+	master := []byte("break glass master key")
+	shares, err := shamir.Split(master, 3, 2)
+	if !assert.Nil(t, err) {
+		return
+	}
+	for i, share := range shares {
+		assert.Nil(t, os.WriteFile(path.Join(baseDir, "share"+string(rune('1'+i))), []byte(share.Encode()+"\n"), 0600))
+	}
+
+	var testCases = []struct {
+		description string
+		raw         string
+		hasError    bool
+	}{
+		{description: "2 of 3 shares", raw: "aes://shamir" + baseDir + "/share1," + baseDir + "/share3"},
+		{description: "file URL shares", raw: "aes://shamir/file://" + baseDir + "/share2,file://" + baseDir + "/share3"},
+		{description: "unavailable share", raw: "aes://shamir" + baseDir + "/share1," + baseDir + "/missing," + baseDir + "/share2"},
+		{description: "not enough shares", raw: "aes://shamir" + baseDir + "/share1," + baseDir + "/missing", hasError: true},
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.raw)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.True(t, key.IsShamir(), testCase.description)
+		actual, err := key.Key(ctx, nil)
+		if testCase.hasError {
+			assert.NotNil(t, err, testCase.description)
+			continue
+		}
+		if assert.Nil(t, err, testCase.description) {
+			assert.EqualValues(t, master, actual, testCase.description)
+		}
+	}
+
+	key, _ := kms.NewKey("aes://shamir" + baseDir + "/share1," + baseDir + "/share2")
+	cipher, _ := kms.Lookup(key.Scheme)
+	encrypted, err := cipher.Encrypt(ctx, key, []byte("secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	key, _ = kms.NewKey("aes://shamir" + baseDir + "/share3," + baseDir + "/share2")
+	decrypted, err := cipher.Decrypt(ctx, key, encrypted)
+	assert.Nil(t, err)
+	assert.EqualValues(t, "secret", string(decrypted))
+
+	tampered := *shares[0]
+	tampered.Y = append([]byte{}, shares[0].Y...)
+	tampered.Y[0] ^= 0xff
+	assert.Nil(t, os.WriteFile(path.Join(baseDir, "tampered"), []byte(tampered.Encode()), 0600))
+	key, _ = kms.NewKey("aes://shamir" + baseDir + "/tampered," + baseDir + "/share2")
+	_, err = cipher.Decrypt(ctx, key, encrypted)
+	assert.NotNil(t, err, "wrong combined key fails authenticated decryption")
+}
+
+func TestKey_ShareLocations(t *testing.T) {
+	t.Setenv("HOME", "/home/scy")
+	var testCases = []struct {
+		raw    string
+		expect []string
+	}{
+		{raw: "aes://shamir/tmp/share1,/mnt/share2", expect: []string{"/tmp/share1", "/mnt/share2"}},
+		{raw: "aes://shamir//tmp/share1", expect: []string{"/tmp/share1"}},
+		{raw: "aes://shamir/~/share1, ~/share2", expect: []string{"/home/scy/share1", "/home/scy/share2"}},
+		{raw: "aes://shamir/gs://bucket/share1,file:///tmp/share2", expect: []string{"gs://bucket/share1", "file:///tmp/share2"}},
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.raw)
+		if assert.Nil(t, err, testCase.raw) {
+			assert.EqualValues(t, testCase.expect, key.ShareLocations(), testCase.raw)
+		}
+	}
+}
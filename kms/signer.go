@@ -0,0 +1,30 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+)
+
+// SignerProvider is implemented by ciphers signing with private keys that never leave key management service
+type SignerProvider interface {
+	//Signer returns signer for asymmetric key version, public key is resolved from key management service
+	Signer(ctx context.Context, key *Key) (crypto.Signer, error)
+}
+
+// NewSigner returns signer for key URL, i.e. gcp://kms/projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1
+func NewSigner(ctx context.Context, keyURL string) (crypto.Signer, error) {
+	key, err := NewKey(keyURL)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := Lookup(key.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := cipher.(SignerProvider)
+	if !ok {
+		return nil, fmt.Errorf("%v cipher does not support signing", key.Scheme)
+	}
+	return provider.Signer(ctx, key)
+}
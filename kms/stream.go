@@ -0,0 +1,212 @@
+package kms
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamMagic represents magic bytes prefixing chunked stream payload
+var StreamMagic = []byte{'S', 'C', 'Y', 'S'}
+
+const (
+	streamVersion         = 1
+	streamNoncePrefixSize = 7
+	streamHeaderSize      = 4 + 1 + 4 + streamNoncePrefixSize
+	streamNonceSize       = streamNoncePrefixSize + 4 + 1
+	//DefaultChunkSize represents default stream plaintext chunk size
+	DefaultChunkSize = 64 * 1024
+	//MaxChunkSize represents max stream plaintext chunk size
+	MaxChunkSize = 16 * 1024 * 1024
+)
+
+// StreamCipher is implemented by ciphers supporting chunked authenticated stream encryption
+type StreamCipher interface {
+	//EncryptWriter returns writer encrypting data into w, Close has to be called to seal the final chunk
+	EncryptWriter(ctx context.Context, key *Key, w io.Writer) (io.WriteCloser, error)
+
+	//DecryptReader returns reader decrypting data from r
+	DecryptReader(ctx context.Context, key *Key, r io.Reader) (io.Reader, error)
+}
+
+// IsStream returns true if data starts with stream header
+func IsStream(data []byte) bool {
+	return len(data) >= streamHeaderSize && bytes.HasPrefix(data, StreamMagic)
+}
+
+// streamWriter seals every chunk with a nonce made of a random prefix, chunk counter and last chunk flag,
+// stream header is bound as associated data, so chunks can not be reordered, dropped or truncated unnoticed
+type streamWriter struct {
+	aead    cipher.AEAD
+	writer  io.Writer
+	aad     []byte
+	nonce   []byte
+	counter uint32
+	buffer  []byte
+	sealed  []byte
+	size    int
+	closed  bool
+}
+
+// NewStreamWriter creates chunked authenticated encryption writer, aead nonce size has to be 12 bytes,
+// stream header followed by associated data is authenticated with every chunk
+func NewStreamWriter(aead cipher.AEAD, w io.Writer, chunkSize int, associatedData []byte) (io.WriteCloser, error) {
+	if aead.NonceSize() != streamNonceSize {
+		return nil, fmt.Errorf("unsupported stream nonce size: %v", aead.NonceSize())
+	}
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("invalid stream chunk size: %v", chunkSize)
+	}
+	header := make([]byte, streamHeaderSize)
+	copy(header, StreamMagic)
+	header[4] = streamVersion
+	binary.BigEndian.PutUint32(header[5:], uint32(chunkSize))
+	if _, err := io.ReadFull(rand.Reader, header[9:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	result := &streamWriter{aead: aead, writer: w, aad: append(header, associatedData...), size: chunkSize,
+		nonce:  make([]byte, streamNonceSize),
+		buffer: make([]byte, 0, chunkSize),
+		sealed: make([]byte, 0, chunkSize+aead.Overhead()),
+	}
+	copy(result.nonce, header[9:])
+	return result, nil
+}
+
+// Write encrypts data, full chunks are sealed once more data follows
+func (s *streamWriter) Write(data []byte) (int, error) {
+	if s.closed {
+		return 0, fmt.Errorf("stream writer was closed")
+	}
+	written := 0
+	for len(data) > 0 {
+		if len(s.buffer) == s.size {
+			if err := s.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buffer[len(s.buffer):s.size], data)
+		s.buffer = s.buffer[:len(s.buffer)+n]
+		data = data[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the final chunk, it does not close the underlying writer
+func (s *streamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.seal(true)
+}
+
+func (s *streamWriter) seal(last bool) error {
+	if s.counter == ^uint32(0) {
+		return fmt.Errorf("stream is too large")
+	}
+	setStreamNonce(s.nonce, s.counter, last)
+	s.counter++
+	s.sealed = s.aead.Seal(s.sealed[:0], s.nonce, s.buffer, s.aad)
+	s.buffer = s.buffer[:0]
+	_, err := s.writer.Write(s.sealed)
+	return err
+}
+
+type streamReader struct {
+	aead    cipher.AEAD
+	reader  *bufio.Reader
+	aad     []byte
+	nonce   []byte
+	counter uint32
+	chunk   []byte
+	plain   []byte
+	done    bool
+}
+
+// NewStreamReader creates chunked authenticated decryption reader, r has to start with stream header
+func NewStreamReader(aead cipher.AEAD, r io.Reader, associatedData []byte) (io.Reader, error) {
+	if aead.NonceSize() != streamNonceSize {
+		return nil, fmt.Errorf("unsupported stream nonce size: %v", aead.NonceSize())
+	}
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("invalid stream header: %w", err)
+	}
+	if !bytes.HasPrefix(header, StreamMagic) {
+		return nil, fmt.Errorf("invalid stream header: magic was missing")
+	}
+	if header[4] != streamVersion {
+		return nil, fmt.Errorf("unsupported stream version: %v", header[4])
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[5:]))
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("invalid stream chunk size: %v", chunkSize)
+	}
+	result := &streamReader{aead: aead, reader: bufio.NewReader(r), aad: append(header, associatedData...),
+		nonce: make([]byte, streamNonceSize),
+		chunk: make([]byte, chunkSize+aead.Overhead()),
+	}
+	copy(result.nonce, header[9:])
+	return result, nil
+}
+
+// Read reads decrypted data
+func (s *streamReader) Read(data []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(data, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+func (s *streamReader) open() error {
+	n, err := io.ReadFull(s.reader, s.chunk)
+	last := false
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err = s.reader.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if n < s.aead.Overhead() {
+		return fmt.Errorf("invalid stream: truncated chunk %v", s.counter)
+	}
+	setStreamNonce(s.nonce, s.counter, last)
+	s.counter++
+	if s.plain, err = s.aead.Open(s.chunk[:0], s.nonce, s.chunk[:n], s.aad); err != nil {
+		return fmt.Errorf("failed to decrypt stream chunk %v: %w", s.counter-1, err)
+	}
+	s.done = last
+	return nil
+}
+
+func setStreamNonce(nonce []byte, counter uint32, last bool) {
+	binary.BigEndian.PutUint32(nonce[streamNoncePrefixSize:], counter)
+	nonce[streamNonceSize-1] = 0
+	if last {
+		nonce[streamNonceSize-1] = 1
+	}
+}
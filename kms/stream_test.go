@@ -0,0 +1,89 @@
+package kms_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	"io"
+	"testing"
+)
+
+func newTestAEAD(t *testing.T) cipher.AEAD {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{7}, 32))
+	assert.Nil(t, err)
+	aead, err := cipher.NewGCM(block)
+	assert.Nil(t, err)
+	return aead
+}
+
+func encryptStream(t *testing.T, aead cipher.AEAD, data []byte, chunkSize int) []byte {
+	buf := new(bytes.Buffer)
+	writer, err := kms.NewStreamWriter(aead, buf, chunkSize, nil)
+	if !assert.Nil(t, err) {
+		return nil
+	}
+	for i := 0; i < len(data); i += 7 { //write in uneven pieces
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err = writer.Write(data[i:end])
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestNewStreamWriter(t *testing.T) {
+	aead := newTestAEAD(t)
+	chunkSize := 64
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize, 5*chunkSize + 13} {
+		data := make([]byte, size)
+		_, _ = rand.Read(data)
+		encrypted := encryptStream(t, aead, data, chunkSize)
+		assert.True(t, kms.IsStream(encrypted), size)
+		reader, err := kms.NewStreamReader(aead, bytes.NewReader(encrypted), nil)
+		if !assert.Nil(t, err, size) {
+			continue
+		}
+		actual, err := io.ReadAll(reader)
+		assert.Nil(t, err, size)
+		assert.EqualValues(t, data, actual, size)
+	}
+}
+
+func TestNewStreamReader_Tampered(t *testing.T) {
+	aead := newTestAEAD(t)
+	chunkSize := 64
+	data := bytes.Repeat([]byte("0123456789"), 30)
+	encrypted := encryptStream(t, aead, data, chunkSize)
+	headerSize, sealedSize := 16, chunkSize+aead.Overhead()
+
+	var testCases = []struct {
+		description string
+		mutate      func(data []byte) []byte
+	}{
+		{description: "truncated at chunk boundary", mutate: func(data []byte) []byte { return data[:headerSize+2*sealedSize] }},
+		{description: "truncated inside chunk", mutate: func(data []byte) []byte { return data[:len(data)-3] }},
+		{description: "flipped byte", mutate: func(data []byte) []byte { data[headerSize+sealedSize+5] ^= 1; return data }},
+		{description: "modified header", mutate: func(data []byte) []byte { data[10] ^= 1; return data }},
+		{description: "reordered chunks", mutate: func(data []byte) []byte {
+			result := append([]byte{}, data[:headerSize]...)
+			result = append(result, data[headerSize+sealedSize:headerSize+2*sealedSize]...)
+			result = append(result, data[headerSize:headerSize+sealedSize]...)
+			return append(result, data[headerSize+2*sealedSize:]...)
+		}},
+		{description: "header only", mutate: func(data []byte) []byte { return data[:headerSize] }},
+	}
+	for _, testCase := range testCases {
+		mutated := testCase.mutate(append([]byte{}, encrypted...))
+		reader, err := kms.NewStreamReader(aead, bytes.NewReader(mutated), nil)
+		if err == nil {
+			_, err = io.ReadAll(reader)
+		}
+		assert.NotNil(t, err, testCase.description)
+	}
+}
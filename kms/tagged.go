@@ -0,0 +1,237 @@
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TagName represents struct tag name declaring encrypted fields, i.e. `scy:"encrypt,into=EncryptedPassword"`,
+// without into the field holds base64 encoded ciphertext itself
+const TagName = "scy"
+
+// TaggedSecurable returns Securable ciphering target fields tagged with scy:"encrypt" in nested structs, pointers,
+// slices, arrays and maps (interface fields are not inspected), ok is false if target type has no tagged fields
+func TaggedSecurable(target interface{}) (Securable, bool) {
+	if target == nil {
+		return nil, false
+	}
+	if _, ok := target.(Securable); ok {
+		return nil, false
+	}
+	if !hasTaggedFields(reflect.TypeOf(target)) {
+		return nil, false
+	}
+	return &taggedSecurable{target: target}, true
+}
+
+type taggedSecurable struct {
+	target interface{}
+}
+
+// Cipher encrypts tagged fields into their ciphertext fields, clears plain fields after that, empty fields are skipped
+func (t *taggedSecurable) Cipher(ctx context.Context, key *Key) error {
+	cipher, err := Lookup(key.Scheme)
+	if err != nil {
+		return err
+	}
+	return t.walk(func(path string, plain, encrypted reflect.Value) error {
+		data := fieldBytes(plain)
+		if len(data) == 0 {
+			return nil
+		}
+		ciphertext, err := cipher.Encrypt(ctx, key, data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %v: %w", path, err)
+		}
+		setFieldBytes(plain, nil)
+		encrypted.SetString(base64.StdEncoding.EncodeToString(ciphertext))
+		return nil
+	})
+}
+
+// Decipher decrypts ciphertext fields into tagged fields, clears ciphertext fields after that, empty fields are skipped
+func (t *taggedSecurable) Decipher(ctx context.Context, key *Key) error {
+	cipher, err := Lookup(key.Scheme)
+	if err != nil {
+		return err
+	}
+	return t.walk(func(path string, plain, encrypted reflect.Value) error {
+		if encrypted.String() == "" {
+			return nil
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(encrypted.String())
+		if err != nil {
+			return fmt.Errorf("failed to decode %v: %w", path, err)
+		}
+		data, err := cipher.Decrypt(ctx, key, ciphertext)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %v: %w", path, err)
+		}
+		encrypted.SetString("")
+		setFieldBytes(plain, data)
+		return nil
+	})
+}
+
+func (t *taggedSecurable) walk(visit func(path string, plain, encrypted reflect.Value) error) error {
+	value := reflect.ValueOf(t.target)
+	if value.Kind() != reflect.Ptr {
+		return fmt.Errorf("expected pointer, but had: %T", t.target)
+	}
+	return walkTagged(value, "", visit)
+}
+
+// walkTagged calls visit with plain and ciphertext field of every tagged field, path is a JSON path like location
+func walkTagged(value reflect.Value, path string, visit func(path string, plain, encrypted reflect.Value) error) error {
+	if !hasTaggedFields(value.Type()) {
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return walkTagged(value.Elem(), path, visit)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := walkTagged(value.Index(i), fmt.Sprintf("%v[%v]", path, i), visit); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			clone := reflect.New(iter.Value().Type()).Elem()
+			clone.Set(iter.Value())
+			if err := walkTagged(clone, fmt.Sprintf("%v[%v]", path, iter.Key()), visit); err != nil {
+				return err
+			}
+			value.SetMapIndex(iter.Key(), clone)
+		}
+	case reflect.Struct:
+		aType := value.Type()
+		for i := 0; i < aType.NumField(); i++ {
+			field := aType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldPath := field.Name
+			if path != "" {
+				fieldPath = path + "." + field.Name
+			}
+			tag, err := parseTag(field)
+			if err != nil {
+				return err
+			}
+			if tag == nil {
+				if err = walkTagged(value.Field(i), fieldPath, visit); err != nil {
+					return err
+				}
+				continue
+			}
+			encrypted := value.Field(i)
+			if tag.into != "" {
+				if into, ok := aType.FieldByName(tag.into); !ok || into.Type.Kind() != reflect.String || into.PkgPath != "" {
+					return fmt.Errorf("%v tagged field %v into field %v has to be exported string field of %v", TagName, field.Name, tag.into, aType)
+				}
+				encrypted = value.FieldByName(tag.into)
+			}
+			if err = visit(fieldPath, value.Field(i), encrypted); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type fieldTag struct {
+	into string
+}
+
+// parseTag parses scy field tag, it returns nil for fields without tag
+func parseTag(field reflect.StructField) (*fieldTag, error) {
+	tag, ok := field.Tag.Lookup(TagName)
+	if !ok {
+		return nil, nil
+	}
+	elements := strings.Split(tag, ",")
+	if strings.TrimSpace(elements[0]) != "encrypt" {
+		return nil, fmt.Errorf("unsupported %v tag: %v on field %v", TagName, tag, field.Name)
+	}
+	result := &fieldTag{}
+	for _, element := range elements[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(element), "=")
+		if name != "into" || value == "" {
+			return nil, fmt.Errorf("unsupported %v tag option: %v on field %v", TagName, element, field.Name)
+		}
+		result.into = value
+	}
+	if !isBytesOrString(field.Type) {
+		return nil, fmt.Errorf("unsupported %v tagged field %v type: %v", TagName, field.Name, field.Type)
+	}
+	if result.into == "" && field.Type.Kind() != reflect.String {
+		return nil, fmt.Errorf("%v tagged field %v requires into option, ciphertext is stored in string field", TagName, field.Name)
+	}
+	return result, nil
+}
+
+func isBytesOrString(aType reflect.Type) bool {
+	return aType.Kind() == reflect.String || (aType.Kind() == reflect.Slice && aType.Elem().Kind() == reflect.Uint8)
+}
+
+var taggedTypes sync.Map
+
+// hasTaggedFields returns true if type or any of its nested types has scy tagged fields
+func hasTaggedFields(aType reflect.Type) bool {
+	if cached, ok := taggedTypes.Load(aType); ok {
+		return cached.(bool)
+	}
+	result := inspectTaggedFields(aType, map[reflect.Type]bool{})
+	taggedTypes.Store(aType, result)
+	return result
+}
+
+// inspectTaggedFields inspects type recursively, visited guards recursive types
+func inspectTaggedFields(aType reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[aType] {
+		return false
+	}
+	visited[aType] = true
+	result := false
+	switch aType.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		result = inspectTaggedFields(aType.Elem(), visited)
+	case reflect.Struct:
+		for i := 0; i < aType.NumField() && !result; i++ {
+			field := aType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if _, ok := field.Tag.Lookup(TagName); ok {
+				result = true
+				continue
+			}
+			result = inspectTaggedFields(field.Type, visited)
+		}
+	}
+	return result
+}
+
+func fieldBytes(value reflect.Value) []byte {
+	if value.Kind() == reflect.String {
+		return []byte(value.String())
+	}
+	return value.Bytes()
+}
+
+func setFieldBytes(value reflect.Value, data []byte) {
+	if value.Kind() == reflect.String {
+		value.SetString(string(data))
+		return
+	}
+	value.SetBytes(data)
+}
@@ -0,0 +1,114 @@
+package kms_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/kms/memory"
+	"testing"
+)
+
+type taggedCredential struct {
+	Name             string
+	Password         string `json:",omitempty" scy:"encrypt,into=EncryptedSecret"`
+	EncryptedSecret  string `json:",omitempty"`
+	Token            string `json:",omitempty" scy:"encrypt"`
+	Payload          []byte `json:",omitempty" scy:"encrypt,into=EncryptedPayload"`
+	EncryptedPayload string `json:",omitempty"`
+}
+
+type taggedConfig struct {
+	Primary   taggedCredential
+	Fallback  *taggedCredential
+	Replicas  []taggedCredential
+	Services  map[string]taggedCredential
+	Endpoints map[string]*taggedCredential
+	Next      *taggedConfig
+}
+
+func TestTaggedSecurable(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key, err := kms.NewKey(cipher.Key("tagged"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	newConfig := func() *taggedConfig {
+		return &taggedConfig{
+			Primary:   taggedCredential{Name: "primary", Password: "pass-primary", Token: "token-primary", Payload: []byte("payload-primary")},
+			Fallback:  &taggedCredential{Name: "fallback", Password: "pass-fallback"},
+			Replicas:  []taggedCredential{{Name: "r1", Password: "pass-r1"}, {Name: "r2"}},
+			Services:  map[string]taggedCredential{"svc": {Password: "pass-svc"}},
+			Endpoints: map[string]*taggedCredential{"ep": {Token: "token-ep"}},
+			Next:      &taggedConfig{Primary: taggedCredential{Password: "pass-next"}},
+		}
+	}
+
+	var testCases = []struct {
+		description string
+		target      interface{}
+		expectOk    bool
+	}{
+		{description: "nested struct", target: newConfig(), expectOk: true},
+		{description: "slice", target: &[]taggedCredential{{Password: "pass-slice"}}, expectOk: true},
+		{description: "map", target: &map[string]taggedCredential{"a": {Token: "token-map"}}, expectOk: true},
+		{description: "untagged", target: &struct{ Password string }{Password: "plain"}, expectOk: false},
+		{description: "nil", target: nil, expectOk: false},
+	}
+	for _, testCase := range testCases {
+		securable, ok := kms.TaggedSecurable(testCase.target)
+		if !assert.EqualValues(t, testCase.expectOk, ok, testCase.description) || !ok {
+			continue
+		}
+		expect, _ := json.Marshal(testCase.target)
+		if !assert.Nil(t, cipher.VerifySecurable(ctx, securable, cipher.Key("tagged")), testCase.description) {
+			continue
+		}
+		assert.Nil(t, securable.Decipher(ctx, key), testCase.description)
+		actual, _ := json.Marshal(testCase.target)
+		assert.JSONEq(t, string(expect), string(actual), testCase.description)
+	}
+
+	config := newConfig()
+	securable, _ := kms.TaggedSecurable(config)
+	assert.Nil(t, securable.Cipher(ctx, key))
+	assert.EqualValues(t, "", config.Services["svc"].Password)
+	assert.NotEqual(t, "", config.Services["svc"].EncryptedSecret, "map value")
+	assert.NotEqual(t, "token-ep", config.Endpoints["ep"].Token, "in place ciphertext")
+	assert.EqualValues(t, "", config.Replicas[1].EncryptedSecret, "empty field")
+	config.Replicas[0].EncryptedSecret = "%%%"
+	err = securable.Decipher(ctx, key)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "Replicas[0].Password")
+	}
+}
+
+func TestTaggedSecurable_InvalidTag(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key, _ := kms.NewKey(cipher.Key("tagged"))
+	var testCases = []struct {
+		description string
+		target      interface{}
+	}{
+		{description: "missing into field", target: &struct {
+			Password string `scy:"encrypt,into=Missing"`
+		}{Password: "x"}},
+		{description: "unsupported option", target: &struct {
+			Password string `scy:"encrypt,as=base64"`
+		}{Password: "x"}},
+		{description: "unsupported type", target: &struct {
+			Pin int `scy:"encrypt"`
+		}{Pin: 1}},
+		{description: "bytes without into", target: &struct {
+			Payload []byte `scy:"encrypt"`
+		}{Payload: []byte("x")}},
+	}
+	for _, testCase := range testCases {
+		securable, ok := kms.TaggedSecurable(testCase.target)
+		if assert.True(t, ok, testCase.description) {
+			assert.NotNil(t, securable.Cipher(ctx, key), testCase.description)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/viant/scy/kms"
+	"github.com/viant/scy/vault"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Scheme represents vault transit cipher scheme
+const Scheme = "vault"
+
+// Cipher represents vault transit engine cipher, key format: vault://transit/my-key where host is transit mount
+type Cipher struct {
+	client *vault.Client
+	mux    sync.Mutex
+}
+
+// Algorithm returns cipher algorithm name
+func (c *Cipher) Algorithm() string {
+	return "vault-transit"
+}
+
+// Authenticated returns true, decryption verifies ciphertext integrity
+func (c *Cipher) Authenticated() bool {
+	return true
+}
+
+// BindsAssociatedData returns true, context associated data is sent as transit associated_data (AEAD key types)
+func (c *Cipher) BindsAssociatedData() bool {
+	return true
+}
+
+// Encrypt encrypts data with supplied key
+func (c *Cipher) Encrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	mount, name, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.vaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request := withAssociatedData(ctx, map[string]string{"plaintext": base64.StdEncoding.EncodeToString(data)})
+	response := &struct {
+		Ciphertext string `json:"ciphertext"`
+	}{}
+	if err = client.Do(ctx, http.MethodPost, mount+"/encrypt/"+name, request, response); err != nil {
+		return nil, fmt.Errorf("failed to encrypt with key %v, %w", key.Raw, err)
+	}
+	if response.Ciphertext == "" {
+		return nil, fmt.Errorf("failed to encrypt with key %v: empty ciphertext", key.Raw)
+	}
+	return []byte(response.Ciphertext), nil
+}
+
+// Decrypt decrypts data with supplied key
+func (c *Cipher) Decrypt(ctx context.Context, key *kms.Key, data []byte) ([]byte, error) {
+	mount, name, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.vaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request := withAssociatedData(ctx, map[string]string{"ciphertext": string(data)})
+	response := &struct {
+		Plaintext string `json:"plaintext"`
+	}{}
+	if err = client.Do(ctx, http.MethodPost, mount+"/decrypt/"+name, request, response); err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %v, %w", key.Raw, err)
+	}
+	return base64.StdEncoding.DecodeString(response.Plaintext)
+}
+
+// vaultClient returns cipher client, the registered cipher creates client from VAULT_ADDR and VAULT_TOKEN on first use
+func (c *Cipher) vaultClient(ctx context.Context) (*vault.Client, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.client == nil {
+		client, err := vault.New(ctx, &vault.Config{})
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+	}
+	return c.client, nil
+}
+
+// withAssociatedData adds base64 encoded context associated data to transit request
+func withAssociatedData(ctx context.Context, request map[string]string) map[string]string {
+	if data := kms.AssociatedData(ctx); len(data) > 0 {
+		request["associated_data"] = base64.StdEncoding.EncodeToString(data)
+	}
+	return request
+}
+
+func parseKey(key *kms.Key) (string, string, error) {
+	name := strings.Trim(key.Path, "/")
+	if key.Kind == "" || name == "" {
+		return "", "", fmt.Errorf("invalid vault key: %v, expected: vault://transit/my-key", key.Raw)
+	}
+	return key.Kind, name, nil
+}
+
+// New creates vault transit cipher
+func New(client *vault.Client) *Cipher {
+	return &Cipher{client: client}
+}
@@ -0,0 +1,102 @@
+package vault_test
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy/cred"
+	"github.com/viant/scy/kms"
+	kvault "github.com/viant/scy/kms/vault"
+	"github.com/viant/scy/vault"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"context"
+)
+
+// transitStandIn emulates vault transit encrypt/decrypt endpoints
+func transitStandIn(token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		request := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/") // v1/transit/encrypt/name
+		if len(parts) != 4 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		prefix := "vault:v1:" + parts[3] + ":" + request["associated_data"] + ":" //associated data is authenticated like AEAD key types
+		switch parts[2] {
+		case "encrypt":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": prefix + request["plaintext"]}})
+		case "decrypt":
+			if !strings.HasPrefix(request["ciphertext"], prefix) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["cipher: message authentication failed"]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": request["ciphertext"][len(prefix):]}})
+		}
+	}))
+}
+
+func TestCipher_Encrypt(t *testing.T) {
+	server := transitStandIn("s.test")
+	defer server.Close()
+	ctx := context.Background()
+	client := vault.NewWithSecretKey(&vault.Config{Address: server.URL}, &cred.SecretKey{Key: "token", Secret: "s.test"})
+	kms.Register(kvault.Scheme, kvault.New(client))
+
+	var testCases = []struct {
+		description string
+		key         string
+		input       string
+	}{
+		{description: "transit key", key: "vault://transit/app-key", input: "secret sequence @123!@#"},
+		{description: "binary payload", key: "vault://transit/app-key", input: string([]byte{0x0, 0xFF, 0x1})},
+	}
+	for _, testCase := range testCases {
+		key, err := kms.NewKey(testCase.key)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		srv, err := kms.Lookup(key.Scheme)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		encrypted, err := srv.Encrypt(ctx, key, []byte(testCase.input))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(string(encrypted), "vault:v1:"), testCase.description)
+		actual, err := srv.Decrypt(ctx, key, encrypted)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, testCase.input, string(actual), testCase.description)
+	}
+
+	key, _ := kms.NewKey("vault://transit/other-key")
+	_, err := kvault.New(client).Decrypt(ctx, key, []byte("vault:v1:app-key::YWJj"))
+	assert.NotNil(t, err, "wrong key")
+
+	assert.True(t, kms.BindsAssociatedData(kvault.New(client)))
+	bound, _ := kms.NewKey("vault://transit/app-key")
+	encrypted, err := kvault.New(client).Encrypt(kms.WithAssociatedData(ctx, []byte("db.json")), bound, []byte("bound"))
+	if assert.Nil(t, err) {
+		_, err = kvault.New(client).Decrypt(kms.WithAssociatedData(ctx, []byte("api.json")), bound, encrypted)
+		assert.NotNil(t, err, "moved ciphertext")
+		decrypted, err := kvault.New(client).Decrypt(kms.WithAssociatedData(ctx, []byte("db.json")), bound, encrypted)
+		assert.Nil(t, err)
+		assert.EqualValues(t, "bound", string(decrypted))
+	}
+
+	denied := vault.NewWithSecretKey(&vault.Config{Address: server.URL}, &cred.SecretKey{Key: "token", Secret: "invalid"})
+	_, err = kvault.New(denied).Encrypt(ctx, key, []byte("abc"))
+	assert.NotNil(t, err, "invalid token")
+}
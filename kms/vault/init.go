@@ -0,0 +1,7 @@
+package vault
+
+import "github.com/viant/scy/kms"
+
+func init() {
+	kms.Register(Scheme, &Cipher{})
+}
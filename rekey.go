@@ -0,0 +1,366 @@
+package scy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/viant/afs/file"
+	"github.com/viant/afs/url"
+	"github.com/viant/scy/kms"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// encryptedFieldPrefix is a prefix of kms.Securable fields holding base64 encoded ciphertext (i.e. EncryptedPassword)
+const encryptedFieldPrefix = "encrypted"
+
+// RekeyOption represents Rekey and RekeyAll option
+type RekeyOption func(o *rekeyOptions)
+
+type rekeyOptions struct {
+	unauthenticated bool
+}
+
+// WithUnauthenticatedRekey allows re-encrypting secrets decrypted with a cipher that can not detect a wrong key (i.e. blowfish),
+// a wrong old key decrypts to garbage that would be re-encrypted, so the old key has to be verified by the caller
+func WithUnauthenticatedRekey() RekeyOption {
+	return func(o *rekeyOptions) {
+		o.unauthenticated = true
+	}
+}
+
+// verify returns error if old key cipher can not detect a wrong key and unauthenticated rekey was not allowed
+func (o *rekeyOptions) verify(key *kms.Key) error {
+	if o.unauthenticated || kms.Authenticates(key) {
+		return nil
+	}
+	return fmt.Errorf("%v cipher can not detect a wrong key, unauthenticated rekey was not allowed", key.Scheme)
+}
+
+func newRekeyOptions(options []RekeyOption) *rekeyOptions {
+	result := &rekeyOptions{}
+	for _, option := range options {
+		option(result)
+	}
+	return result
+}
+
+// RekeyReport represents bulk re-encryption outcome
+type RekeyReport struct {
+	DryRun  bool
+	Rekeyed []string
+	Skipped []string
+	Failed  map[string]error
+}
+
+// HasFailures returns true if any secret failed to re-encrypt
+func (r *RekeyReport) HasFailures() bool {
+	return len(r.Failed) > 0
+}
+
+// Error returns combined failure error or nil
+func (r *RekeyReport) Error() error {
+	if !r.HasFailures() {
+		return nil
+	}
+	var URLs = make([]string, 0, len(r.Failed))
+	for URL := range r.Failed {
+		URLs = append(URLs, URL)
+	}
+	sort.Strings(URLs)
+	var messages []string
+	for _, URL := range URLs {
+		messages = append(messages, fmt.Sprintf("%v: %v", URL, r.Failed[URL]))
+	}
+	return fmt.Errorf("failed to rekey %v secret(s): %v", len(URLs), strings.Join(messages, "; "))
+}
+
+// Rekey re-encrypts resource secret with a new key, both raw payloads and kms.Securable documents (Encrypted* fields) are supported,
+// scy tagged fields are re-encrypted when resource target type declares them (see kms.TaggedSecurable),
+// resource.Key is used to decrypt secrets without a ciphertext header, a secret without encrypted data fails,
+// a secret whose ciphertext header names another key fails, secrets decrypted with a cipher that can not detect a wrong key
+// (i.e. blowfish) fail unless WithUnauthenticatedRekey is used
+func (s *Service) Rekey(ctx context.Context, resource *Resource, newKey string, options ...RekeyOption) error {
+	changed, err := s.rekey(ctx, resource, newKey, false, newRekeyOptions(options))
+	if err == nil && !changed {
+		return fmt.Errorf("failed to rekey %v: no encrypted data was found", resource.URL)
+	}
+	return err
+}
+
+// RekeyAll walks resource location and re-encrypts every secret it finds with a new key, with dryRun secrets are only decrypted
+func (s *Service) RekeyAll(ctx context.Context, resource *Resource, newKey string, dryRun bool, options ...RekeyOption) (*RekeyReport, error) {
+	if err := resource.Validate(); err != nil {
+		return nil, err
+	}
+	if newKey == "" {
+		return nil, fmt.Errorf("new key was empty")
+	}
+	report := &RekeyReport{DryRun: dryRun, Failed: map[string]error{}}
+	if err := s.rekeyAll(ctx, resource.URL, resource, newKey, report, newRekeyOptions(options)); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *Service) rekeyAll(ctx context.Context, URL string, template *Resource, newKey string, report *RekeyReport, opts *rekeyOptions) error {
+	objects, err := s.fs.List(ctx, URL, template.Options...)
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, object := range objects {
+		names[object.Name()] = true
+	}
+	for _, object := range objects {
+		if !object.IsDir() && isGeneration(object.Name(), names) { //generations keep previous key ciphertext
+			continue
+		}
+		if object.IsDir() {
+			if url.Equals(object.URL(), URL) {
+				continue
+			}
+			if err = s.rekeyAll(ctx, object.URL(), template, newKey, report, opts); err != nil {
+				return err
+			}
+			continue
+		}
+		resource := &Resource{URL: object.URL(), Key: template.Key, MaxRetry: template.MaxRetry, TimeoutMs: template.TimeoutMs, Options: template.Options, Generations: template.Generations, target: template.target}
+		changed, err := s.rekey(ctx, resource, newKey, report.DryRun, opts)
+		switch {
+		case err != nil:
+			report.Failed[resource.URL] = err
+		case changed:
+			report.Rekeyed = append(report.Rekeyed, resource.URL)
+		default:
+			report.Skipped = append(report.Skipped, resource.URL)
+		}
+	}
+	return nil
+}
+
+// rekey re-encrypts resource payload, it returns false for payloads without encrypted data
+func (s *Service) rekey(ctx context.Context, resource *Resource, newKey string, dryRun bool, opts *rekeyOptions) (bool, error) {
+	newKeyValue, newCipher, err := s.loadKeyCipher(newKey)
+	if err != nil {
+		return false, err
+	}
+	if newKeyValue == nil {
+		return false, fmt.Errorf("new key was empty")
+	}
+	data, err := s.download(ctx, resource, resource.Data)
+	if err != nil {
+		return false, err
+	}
+	header, data, err := kms.DecodeHeader(data)
+	if err != nil {
+		return false, err
+	}
+	format := Format(resource.URL)
+	isText := isTextDocument(format, data)
+	var payload []byte
+	if header == nil && (isJson(data) || format == FormatYAML || isText) {
+		if !isText && format != FormatYAML {
+			format = FormatJSON
+		}
+		rekeyFields := s.rekeyFields
+		if resource.target != nil {
+			if _, ok := kms.TaggedSecurable(reflect.New(resource.target).Interface()); ok {
+				rekeyFields = s.rekeyTagged
+			}
+		}
+		if payload, err = rekeyFields(ctx, resource, data, format, newKeyValue, newCipher, opts); err != nil || payload == nil {
+			return false, err
+		}
+	} else {
+		if header == nil && resource.Key == "" {
+			return false, nil
+		}
+		key, cipher, err := s.resolveKeyCipher(resource.Key, header)
+		if err != nil {
+			return false, err
+		}
+		if key == nil {
+			return false, fmt.Errorf("key is required to decrypt %v payload: %v", header.Algorithm, resource.URL)
+		}
+		if header != nil && header.KeyID != "" && !key.HasID(header.KeyID) {
+			return false, fmt.Errorf("ciphertext was encrypted with key %v, not %v", header.KeyID, key.ID())
+		}
+		if err = opts.verify(key); err != nil {
+			return false, err
+		}
+		decryptCtx := ctx
+		if header != nil {
+			associatedData, err := resource.associatedData(header.Binding)
+			if err != nil {
+				return false, err
+			}
+			decryptCtx = kms.WithAssociatedData(ctx, associatedData)
+		}
+		if data, _, err = s.decrypt(decryptCtx, s.headerKey(resource.Key, header), key, cipher, data); err != nil {
+			return false, err
+		}
+		encryptCtx, binding := s.bind(ctx, resource, newKeyValue, newCipher)
+		if payload, err = newCipher.Encrypt(encryptCtx, newKeyValue, data); err != nil {
+			return false, err
+		}
+		newHeader := kms.NewHeader(newKeyValue, newCipher)
+		newHeader.Binding = binding
+		if payload, err = newHeader.Encode(payload); err != nil {
+			return false, err
+		}
+	}
+	if dryRun {
+		return true, nil
+	}
+	defer s.InvalidateCache(resource.URL)
+	if err = s.fs.Upload(ctx, resource.URL, file.DefaultFileOsMode, bytes.NewReader(payload), resource.Options...); err != nil {
+		return true, err
+	}
+	return true, s.recordGeneration(ctx, resource)
+}
+
+// rekeyFields re-encrypts Encrypted* document fields, it returns nil payload when document has no encrypted fields,
+// dotenv and INI documents are updated in place
+func (s *Service) rekeyFields(ctx context.Context, resource *Resource, data []byte, format string, newKey *kms.Key, newCipher kms.Cipher, opts *rekeyOptions) ([]byte, error) {
+	var document interface{}
+	var textDoc *textDocument
+	var err error
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &document)
+	case FormatEnv, FormatINI:
+		if textDoc, err = parseTextDocument(data, format == FormatINI); err == nil {
+			document, err = textDoc.Map()
+		}
+	default:
+		err = json.Unmarshal(data, &document)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var key *kms.Key
+	var cipher kms.Cipher
+	var boundCtx context.Context
+	var binding string
+	encryptCtx, _ := s.bind(ctx, resource, newKey, newCipher)
+	count := 0
+	reencrypt := func(value string) (string, error) {
+		if key == nil {
+			if key, cipher, err = s.loadKeyCipher(resource.Key); err != nil {
+				return "", err
+			}
+			if key == nil {
+				return "", fmt.Errorf("key is required to decrypt fields: %v", resource.URL)
+			}
+			if err = opts.verify(key); err != nil {
+				return "", err
+			}
+			boundCtx, binding = s.bind(ctx, resource, key, cipher)
+		}
+		encrypted, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", err
+		}
+		decrypted, err := cipher.Decrypt(boundCtx, key, encrypted)
+		if err != nil && binding != "" {
+			decrypted, err = cipher.Decrypt(ctx, key, encrypted) //unbound or legacy field
+		}
+		if err != nil {
+			return "", err
+		}
+		if encrypted, err = newCipher.Encrypt(encryptCtx, newKey, decrypted); err != nil {
+			return "", err
+		}
+		count++
+		return base64.StdEncoding.EncodeToString(encrypted), nil
+	}
+	if err = rekeyValue(document, reencrypt); err != nil || count == 0 {
+		return nil, err
+	}
+	if textDoc != nil {
+		textDoc.Apply(document.(map[string]interface{}))
+		return textDoc.Bytes(), nil
+	}
+	if format == FormatYAML {
+		return yaml.Marshal(document)
+	}
+	return json.Marshal(document)
+}
+
+// rekeyTagged re-encrypts scy tagged fields of resource target type, ciphertext fields can have any name, it returns nil payload
+// when no tagged field was encrypted
+func (s *Service) rekeyTagged(ctx context.Context, resource *Resource, data []byte, format string, newKey *kms.Key, newCipher kms.Cipher, opts *rekeyOptions) ([]byte, error) {
+	value := reflect.New(resource.target).Interface()
+	if err := Unmarshal(resource.URL, data, value); err != nil {
+		return nil, err
+	}
+	securable, _ := kms.TaggedSecurable(value)
+	key, cipher, err := s.loadKeyCipher(resource.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("key is required to decrypt fields: %v", resource.URL)
+	}
+	encrypted, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	boundCtx, binding := s.bind(ctx, resource, key, cipher)
+	if err = securable.Decipher(boundCtx, key); err != nil && binding != "" {
+		err = securable.Decipher(ctx, key) //unbound or legacy fields
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deciphered, err := json.Marshal(value); err != nil || bytes.Equal(encrypted, deciphered) {
+		return nil, err
+	}
+	if err = opts.verify(key); err != nil {
+		return nil, err
+	}
+	encryptCtx, _ := s.bind(ctx, resource, newKey, newCipher)
+	if err = securable.Cipher(encryptCtx, newKey); err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(value)
+	case FormatEnv, FormatINI:
+		return marshalText(data, format == FormatINI, value)
+	}
+	return json.Marshal(value)
+}
+
+func rekeyValue(value interface{}, reencrypt func(value string) (string, error)) error {
+	switch actual := value.(type) {
+	case map[string]interface{}:
+		for k, v := range actual {
+			if text, ok := v.(string); ok {
+				if text == "" || !strings.HasPrefix(strings.ToLower(k), encryptedFieldPrefix) {
+					continue
+				}
+				var err error
+				if actual[k], err = reencrypt(text); err != nil {
+					return fmt.Errorf("failed to rekey %v: %w", k, err)
+				}
+				continue
+			}
+			if err := rekeyValue(v, reencrypt); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range actual {
+			if err := rekeyValue(item, reencrypt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
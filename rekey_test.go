@@ -0,0 +1,171 @@
+package scy_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/cred"
+	"github.com/viant/scy/kms/memory"
+	"os"
+	"path"
+	"sort"
+	"testing"
+)
+
+func TestService_RekeyAll(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyRekeyTestKey", "rotated key")
+	baseURL := path.Join(os.TempDir(), "scy_rekey")
+	_ = os.RemoveAll(baseURL)
+	if !assert.Nil(t, os.MkdirAll(path.Join(baseURL, "nested"), 0700)) {
+		return
+	}
+	defer os.RemoveAll(baseURL)
+
+	oldKey, newKey := "blowfish://default", "blowfish://env/scyRekeyTestKey"
+	rawURL := path.Join(baseURL, "raw.sec")
+	basicURL := path.Join(baseURL, "nested", "basic.json")
+	plainURL := path.Join(baseURL, "plain.json")
+	invalidURL := path.Join(baseURL, "invalid.json")
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret("this is secret", scy.NewResource("", rawURL, oldKey))))
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret(&cred.Basic{Username: "Bob", Password: "ch@nge!Me"}, scy.NewResource("", basicURL, oldKey))))
+	assert.Nil(t, os.WriteFile(plainURL, []byte(`{"Username":"Bob"}`), 0600))
+	assert.Nil(t, os.WriteFile(invalidURL, []byte(`{"Username":"Bob","EncryptedPassword":"%%%"}`), 0600))
+	before, _ := os.ReadFile(rawURL)
+
+	report, err := srv.RekeyAll(ctx, scy.NewResource("", baseURL, oldKey), newKey, true)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, report.Failed, 3, "blowfish can not detect a wrong key")
+	assert.EqualValues(t, []string{fileURL(plainURL)}, report.Skipped)
+
+	report, err = srv.RekeyAll(ctx, scy.NewResource("", baseURL, oldKey), newKey, true, scy.WithUnauthenticatedRekey())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, []string{fileURL(basicURL), fileURL(rawURL)}, sorted(report.Rekeyed), "dry run rekeyed")
+	assert.EqualValues(t, []string{fileURL(plainURL)}, report.Skipped, "dry run skipped")
+	assert.Contains(t, report.Failed, fileURL(invalidURL))
+	assert.NotNil(t, report.Error())
+	after, _ := os.ReadFile(rawURL)
+	assert.EqualValues(t, before, after, "dry run should not modify secrets")
+
+	report, err = srv.RekeyAll(ctx, scy.NewResource("", baseURL, oldKey), newKey, false, scy.WithUnauthenticatedRekey())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, report.Rekeyed, 2)
+	assert.Len(t, report.Failed, 1)
+
+	secret, err := srv.Load(ctx, scy.NewResource("", rawURL, newKey))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, newKey, secret.Header.KeyID)
+		assert.EqualValues(t, "this is secret", secret.Target)
+	}
+	secret, err = srv.Load(ctx, scy.NewResource(cred.Basic{}, basicURL, newKey))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "ch@nge!Me", secret.Target.(*cred.Basic).Password)
+	}
+	plain, _ := os.ReadFile(plainURL)
+	assert.EqualValues(t, `{"Username":"Bob"}`, string(plain))
+}
+
+func TestService_Rekey(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyRekeyTestKey", "rotated key")
+	URL := path.Join(os.TempDir(), "scy_rekey_basic.yaml")
+	defer os.Remove(URL)
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(&cred.Basic{Username: "Bob", Password: "ch@nge!Me"}, scy.NewResource("", URL, "blowfish://default")))) {
+		return
+	}
+	assert.NotNil(t, srv.Rekey(ctx, scy.NewResource("", URL, "blowfish://default"), "blowfish://env/scyRekeyTestKey"), "unauthenticated rekey")
+	if !assert.Nil(t, srv.Rekey(ctx, scy.NewResource("", URL, "blowfish://default"), "blowfish://env/scyRekeyTestKey", scy.WithUnauthenticatedRekey())) {
+		return
+	}
+	secret, err := srv.Load(ctx, scy.NewResource(cred.Basic{}, URL, "blowfish://env/scyRekeyTestKey"))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, &cred.Basic{Username: "Bob", Password: "ch@nge!Me"}, secret.Target)
+	}
+}
+
+func TestService_RekeyAll_HeaderKey(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyRekeyOtherKey", "other rekey key")
+	_ = os.Setenv("scyRekeyTestKey", "rotated key")
+	baseURL := t.TempDir()
+	URL := path.Join(baseURL, "a.sec")
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("this is secret", scy.NewResource("", URL, "blowfish://env/scyRekeyOtherKey")))) {
+		return
+	}
+	before, _ := os.ReadFile(URL)
+	cipher := memory.RegisterCleanup(t)
+	memoryURL := path.Join(baseURL, "b.sec")
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("memory secret", scy.NewResource("", memoryURL, cipher.Key("other"))))) {
+		return
+	}
+	memoryBefore, _ := os.ReadFile(memoryURL)
+
+	for _, oldKey := range []string{"blowfish://default", cipher.Key("old")} {
+		report, err := srv.RekeyAll(ctx, scy.NewResource("", baseURL, oldKey), "blowfish://env/scyRekeyTestKey", false, scy.WithUnauthenticatedRekey())
+		if !assert.Nil(t, err, oldKey) {
+			continue
+		}
+		assert.Empty(t, report.Rekeyed, oldKey)
+		assert.Contains(t, report.Failed, fileURL(URL), oldKey)
+		assert.Contains(t, report.Failed, fileURL(memoryURL), oldKey)
+	}
+	after, _ := os.ReadFile(URL)
+	assert.EqualValues(t, before, after, "mismatched old key leaves secret untouched")
+	memoryAfter, _ := os.ReadFile(memoryURL)
+	assert.EqualValues(t, memoryBefore, memoryAfter, "mismatched old key leaves secret untouched")
+	secret, err := srv.Load(ctx, scy.NewResource("", URL, "blowfish://env/scyRekeyOtherKey"))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "this is secret", secret.Target)
+	}
+}
+
+func fileURL(location string) string {
+	return "file://localhost" + location
+}
+
+func sorted(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
+
+type rekeyTagged struct {
+	Name         string
+	Token        string `json:",omitempty" scy:"encrypt"`
+	APIKey       string `json:",omitempty" scy:"encrypt,into=SealedAPIKey"`
+	SealedAPIKey string `json:",omitempty"`
+}
+
+func TestService_Rekey_Tagged(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	oldKey, newKey := cipher.Key("old"), cipher.Key("new")
+	URL := path.Join(t.TempDir(), "tagged.json")
+	srv := scy.New()
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(&rekeyTagged{Name: "app", Token: "t0k3n", APIKey: "k3y"}, scy.NewResource(rekeyTagged{}, URL, oldKey)))) {
+		return
+	}
+	assert.NotNil(t, srv.Rekey(ctx, scy.NewResource("", URL, oldKey), newKey), "untyped resource has no Encrypted* fields")
+	if !assert.Nil(t, srv.Rekey(ctx, scy.NewResource(rekeyTagged{}, URL, oldKey), newKey)) {
+		return
+	}
+	_, err := srv.Load(ctx, scy.NewResource(rekeyTagged{}, URL, oldKey))
+	assert.NotNil(t, err, "old key was retired")
+	secret, err := srv.Load(ctx, scy.NewResource(rekeyTagged{}, URL, newKey))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, &rekeyTagged{Name: "app", Token: "t0k3n", APIKey: "k3y"}, secret.Target)
+	}
+
+	plainURL := path.Join(t.TempDir(), "plain.json")
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret(&rekeyTagged{Name: "plain"}, scy.NewResource(rekeyTagged{}, plainURL, oldKey))))
+	assert.NotNil(t, srv.Rekey(ctx, scy.NewResource(rekeyTagged{}, plainURL, oldKey), newKey), "nothing to rekey")
+}
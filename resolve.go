@@ -0,0 +1,337 @@
+package scy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ReferencePrefix prefixes secret reference strings: scy://<URL>[|<key>][#<field path>],
+// i.e. scy://gcp://secretmanager/projects/p/secrets/db|blowfish://default#Password
+const ReferencePrefix = "scy://"
+
+// maxResolveConcurrency limits number of references loaded at the same time
+const maxResolveConcurrency = 16
+
+// ResolveError represents failed references keyed by JSON path
+type ResolveError struct {
+	Failures map[string]error
+}
+
+// Error returns combined failures message
+func (e *ResolveError) Error() string {
+	var paths = make([]string, 0, len(e.Failures))
+	for path := range e.Failures {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var messages []string
+	for _, path := range paths {
+		messages = append(messages, fmt.Sprintf("%v: %v", path, e.Failures[path]))
+	}
+	return fmt.Sprintf("failed to resolve %v reference(s): %v", len(paths), strings.Join(messages, "; "))
+}
+
+// Resolve replaces reference strings in target with loaded secret values, target has to be a pointer to a struct, map,
+// slice or decoded document (interface{}), references are loaded concurrently, a failure is reported as *ResolveError
+func (s *Service) Resolve(ctx context.Context, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr && value.Kind() != reflect.Map {
+		return fmt.Errorf("expected pointer or map, but had: %T", target)
+	}
+	var slots []*referenceSlot
+	collectReferences(value, "$", nil, &slots)
+	return newResolver(s).resolve(ctx, slots)
+}
+
+// ResolveJSON replaces reference strings in JSON document
+func (s *Service) ResolveJSON(ctx context.Context, data []byte) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if err := s.Resolve(ctx, &document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
+
+// ResolveYAML replaces reference strings in YAML document, comments and ordering are preserved
+func (s *Service) ResolveYAML(ctx context.Context, data []byte) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	var slots []*referenceSlot
+	collectNodeReferences(&document, "$", &slots)
+	if err := newResolver(s).resolve(ctx, slots); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(&document)
+}
+
+// IsReference returns true if text is a secret reference
+func IsReference(text string) bool {
+	return strings.HasPrefix(text, ReferencePrefix) && len(text) > len(ReferencePrefix)
+}
+
+type referenceSlot struct {
+	path      string
+	reference string
+	set       func(value string)
+}
+
+// collectReferences collects reference strings, set assigns value location unless value itself is settable
+func collectReferences(value reflect.Value, path string, set func(string), slots *[]*referenceSlot) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			collectReferences(value.Elem(), path, nil, slots)
+		}
+	case reflect.Interface:
+		if value.IsNil() {
+			return
+		}
+		if set == nil && value.CanSet() {
+			set = func(text string) { value.Set(reflect.ValueOf(text)) }
+		}
+		collectReferences(value.Elem(), path, set, slots)
+	case reflect.String:
+		if set == nil && value.CanSet() {
+			set = value.SetString
+		}
+		if set != nil && IsReference(value.String()) {
+			*slots = append(*slots, &referenceSlot{path: path, reference: value.String(), set: set})
+		}
+	case reflect.Struct:
+		aType := value.Type()
+		for i := 0; i < aType.NumField(); i++ {
+			field := aType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			if tag, ok := field.Tag.Lookup("json"); ok {
+				if tag == "-" {
+					continue
+				}
+				if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+					name = tagName
+				}
+			}
+			collectReferences(value.Field(i), path+"."+name, nil, slots)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			collectReferences(value.Index(i), path+"["+strconv.Itoa(i)+"]", nil, slots)
+		}
+	case reflect.Map:
+		elemType := value.Type().Elem()
+		iter := value.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			setter := func(text string) { value.SetMapIndex(key, reflect.ValueOf(text).Convert(elemType)) }
+			if elemType.Kind() != reflect.String && elemType.Kind() != reflect.Interface {
+				setter = nil
+			}
+			collectReferences(iter.Value(), fmt.Sprintf("%v.%v", path, key), setter, slots)
+		}
+	}
+}
+
+// collectNodeReferences collects YAML string scalar references
+func collectNodeReferences(node *yaml.Node, path string, slots *[]*referenceSlot) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collectNodeReferences(child, path, slots)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			collectNodeReferences(node.Content[i+1], path+"."+node.Content[i].Value, slots)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			collectNodeReferences(child, path+"["+strconv.Itoa(i)+"]", slots)
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" && IsReference(node.Value) {
+			*slots = append(*slots, &referenceSlot{path: path, reference: node.Value, set: func(text string) { node.Value = text }})
+		}
+	}
+}
+
+// resolver loads every referenced resource once per resolution
+type resolver struct {
+	service   *Service
+	mux       sync.Mutex
+	documents map[string]*referencedDocument
+}
+
+type referencedDocument struct {
+	once      sync.Once
+	secret    *Secret
+	err       error
+	generic   sync.Once
+	fields    interface{}
+	fieldsErr error
+}
+
+func newResolver(service *Service) *resolver {
+	return &resolver{service: service, documents: map[string]*referencedDocument{}}
+}
+
+func (r *resolver) resolve(ctx context.Context, slots []*referenceSlot) error {
+	var references = map[string]bool{}
+	for _, slot := range slots {
+		references[slot.reference] = true
+	}
+	var mux sync.Mutex
+	var values = map[string]string{}
+	var failures = map[string]error{}
+	var wg sync.WaitGroup
+	limiter := make(chan bool, maxResolveConcurrency)
+	for reference := range references {
+		wg.Add(1)
+		limiter <- true
+		go func(reference string) {
+			defer func() { <-limiter; wg.Done() }()
+			value, err := r.resolveReference(ctx, reference, nil)
+			mux.Lock()
+			defer mux.Unlock()
+			if err != nil {
+				failures[reference] = err
+				return
+			}
+			values[reference] = value
+		}(reference)
+	}
+	wg.Wait()
+	resolveErr := &ResolveError{Failures: map[string]error{}}
+	for _, slot := range slots {
+		if err, ok := failures[slot.reference]; ok {
+			resolveErr.Failures[slot.path] = err
+			continue
+		}
+		slot.set(values[slot.reference])
+	}
+	if len(resolveErr.Failures) > 0 {
+		return resolveErr
+	}
+	return nil
+}
+
+// resolveReference loads reference value, references resolving to other references are followed, chain detects cycles
+func (r *resolver) resolveReference(ctx context.Context, reference string, chain []string) (string, error) {
+	for _, prev := range chain {
+		if prev == reference {
+			return "", fmt.Errorf("reference cycle: %v", strings.Join(append(chain, reference), " -> "))
+		}
+	}
+	chain = append(chain, reference)
+	location, field, _ := strings.Cut(strings.TrimPrefix(reference, ReferencePrefix), "#")
+	document := r.document(location)
+	document.once.Do(func() {
+		resource := EncodedResource(location).Decode(ctx, reflect.TypeOf(map[string]interface{}{}))
+		document.secret, document.err = r.service.Load(ctx, resource)
+	})
+	if document.err != nil && field == "" {
+		return "", document.err
+	}
+	var value string
+	if document.err == nil {
+		value = document.secret.String()
+	}
+	if field != "" {
+		fieldValue, err := r.field(ctx, location, document, field)
+		if err != nil {
+			return "", err
+		}
+		if value, err = referenceText(fieldValue); err != nil {
+			return "", err
+		}
+	}
+	if IsReference(value) {
+		return r.resolveReference(ctx, value, chain)
+	}
+	return value, nil
+}
+
+// field returns document field value, encrypted fields (i.e. EncryptedPassword) are looked up in deciphered document,
+// documents with encrypted fields fail to load as a whole with a key, so they are always looked up that way
+func (r *resolver) field(ctx context.Context, location string, document *referencedDocument, field string) (interface{}, error) {
+	if document.err == nil {
+		if target, ok := document.secret.Target.(*map[string]interface{}); ok {
+			if value, ok := lookupField(*target, field); ok {
+				return value, nil
+			}
+		}
+	}
+	document.generic.Do(func() {
+		var secret *Secret
+		secret, err := r.service.Load(ctx, EncodedResource(location).Decode(ctx, nil))
+		if err == nil {
+			var data []byte
+			if data, err = json.Marshal(secret.Target); err == nil {
+				err = json.Unmarshal(data, &document.fields)
+			}
+		}
+		document.fieldsErr = err
+	})
+	if document.fieldsErr != nil {
+		return nil, fmt.Errorf("failed to lookup field %v: %w", field, document.fieldsErr)
+	}
+	if value, ok := lookupField(document.fields, field); ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("field %v was not found", field)
+}
+
+func (r *resolver) document(location string) *referencedDocument {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	document, ok := r.documents[location]
+	if !ok {
+		document = &referencedDocument{}
+		r.documents[location] = document
+	}
+	return document
+}
+
+// lookupField returns value of dot separated field path, numeric elements index slices
+func lookupField(document interface{}, field string) (interface{}, bool) {
+	value := document
+	for _, name := range strings.Split(field, ".") {
+		switch actual := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if value, ok = actual[name]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(name)
+			if err != nil || index < 0 || index >= len(actual) {
+				return nil, false
+			}
+			value = actual[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// referenceText returns text of a field value, non string values are JSON encoded
+func referenceText(value interface{}) (string, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
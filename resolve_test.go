@@ -0,0 +1,84 @@
+package scy_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/cred"
+	"os"
+	"path"
+	"testing"
+)
+
+type resolvedConfig struct {
+	DSN      string `json:"dsn"`
+	Database struct {
+		Username string
+		Password string
+	}
+	Tokens  []string
+	Headers map[string]string
+	Extra   interface{}
+}
+
+func TestService_Resolve(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	baseURL := t.TempDir()
+	key := "blowfish://default"
+	dbURL := path.Join(baseURL, "db.json")
+	tokenURL := path.Join(baseURL, "token.txt")
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret(&cred.Basic{Username: "bob", Password: "ch@nge!Me"}, scy.NewResource(cred.Basic{}, dbURL, key))))
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret("t0ken", scy.NewResource("", tokenURL, key))))
+	write := func(name, content string) string {
+		URL := path.Join(baseURL, name)
+		assert.Nil(t, os.WriteFile(URL, []byte(content), 0600))
+		return URL
+	}
+	tokenRef := "scy://" + tokenURL + "|" + key
+	chainURL := write("chain.txt", tokenRef)
+	cycleA := path.Join(baseURL, "a.txt")
+	cycleB := write("b.txt", "scy://"+cycleA)
+	write("a.txt", "scy://"+cycleB)
+
+	config := &resolvedConfig{
+		DSN:     "postgres://db/app",
+		Tokens:  []string{tokenRef, "scy://" + chainURL},
+		Headers: map[string]string{"Authorization": tokenRef},
+		Extra:   map[string]interface{}{"list": []interface{}{"scy://" + dbURL + "|" + key + "#Username"}},
+	}
+	config.Database.Username = "scy://" + dbURL + "|" + key + "#Username"
+	config.Database.Password = "scy://" + dbURL + "|" + key + "#Password"
+	if err := srv.Resolve(ctx, config); !assert.Nil(t, err, err) {
+		return
+	}
+	assert.EqualValues(t, "postgres://db/app", config.DSN)
+	assert.EqualValues(t, "bob", config.Database.Username)
+	assert.EqualValues(t, "ch@nge!Me", config.Database.Password, "encrypted field")
+	assert.EqualValues(t, []string{"t0ken", "t0ken"}, config.Tokens, "chained reference")
+	assert.EqualValues(t, "t0ken", config.Headers["Authorization"])
+	assert.EqualValues(t, []interface{}{"bob"}, config.Extra.(map[string]interface{})["list"])
+
+	failing := &resolvedConfig{Tokens: []string{"scy://" + cycleA, "scy://" + path.Join(baseURL, "missing.txt")}}
+	failing.Database.Username = "scy://" + dbURL + "|" + key + "#Unknown"
+	err := srv.Resolve(ctx, failing)
+	resolveErr := &scy.ResolveError{}
+	if assert.True(t, errors.As(err, &resolveErr)) {
+		assert.Len(t, resolveErr.Failures, 3)
+		assert.Contains(t, resolveErr.Failures["$.Tokens[0]"].Error(), "cycle")
+		assert.NotNil(t, resolveErr.Failures["$.Tokens[1]"])
+		assert.NotNil(t, resolveErr.Failures["$.Database.Username"])
+	}
+
+	data, err := srv.ResolveJSON(ctx, []byte(`{"token":"`+tokenRef+`","port":8080}`))
+	if assert.Nil(t, err) {
+		assert.JSONEq(t, `{"token":"t0ken","port":8080}`, string(data))
+	}
+	data, err = srv.ResolveYAML(ctx, []byte("# service config\nport: 8080\ntoken: "+tokenRef+" # resolved at startup\n"))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "# service config\nport: 8080\ntoken: t0ken # resolved at startup\n", string(data))
+	}
+	_, err = srv.ResolveJSON(ctx, []byte(`{"db":{"password":"scy://`+path.Join(baseURL, "missing.txt")+`"}}`))
+	assert.Contains(t, err.Error(), "$.db.password")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/viant/afs"
+	"github.com/viant/afs/file"
 	"github.com/viant/afs/storage"
 	"github.com/viant/afs/url"
 	"os"
@@ -12,6 +13,13 @@ import (
 	"time"
 )
 
+const (
+	//BindingURL represents ciphertext bound to resource URL
+	BindingURL = "url"
+	//BindingName represents ciphertext bound to resource logical name (Resource.AssociatedData)
+	BindingName = "name"
+)
+
 // Resource represents a secret config
 type Resource struct {
 	Name      string           `json:",omitempty"  yaml:"Name,omitempty"`
@@ -22,7 +30,15 @@ type Resource struct {
 	Fallback  *Resource        `json:",omitempty" yaml:"Fallback,omitempty"`
 	Options   []storage.Option `json:"-" yaml:"-"`
 	Data      []byte           `json:",omitempty" yaml:"Data,omitempty"`
-	target    reflect.Type
+	//AssociatedData is a logical name binding ciphertext to the resource, URL is used when empty,
+	//for a moved resource it can be set to the original URL
+	AssociatedData string `json:",omitempty" yaml:"AssociatedData,omitempty"`
+	Unbound        bool   `json:",omitempty" yaml:"Unbound,omitempty"` //disables binding ciphertext to the resource
+	//Version pins loaded secret version, native version for gcp secretmanager, generation number for other backends
+	Version string `json:",omitempty" yaml:"Version,omitempty"`
+	//Generations is number of generation-suffixed copies (URL.v1, URL.v2...) kept by Store for backends without native versions
+	Generations int `json:",omitempty" yaml:"Generations,omitempty"`
+	target      reflect.Type
 }
 
 func (r *Resource) Timeout() time.Duration {
@@ -46,6 +62,39 @@ func (r *Resource) SetTarget(t reflect.Type) {
 	r.target = t
 }
 
+// binding returns associated data binding kind and data used to encrypt resource, empty kind for unbound resource
+func (r *Resource) binding() (string, []byte) {
+	if r.Unbound {
+		return "", nil
+	}
+	if r.AssociatedData != "" {
+		return BindingName, []byte(r.AssociatedData)
+	}
+	if len(r.Data) > 0 || r.URL == "" || strings.HasPrefix(strings.TrimSpace(r.URL), inlineBase64Prefix) {
+		return "", nil
+	}
+	return BindingURL, []byte(url.Normalize(expandHome(r.URL), file.Scheme))
+}
+
+// associatedData returns associated data used to decrypt resource for binding kind recorded in ciphertext header
+func (r *Resource) associatedData(binding string) ([]byte, error) {
+	switch binding {
+	case "":
+		return nil, nil
+	case BindingName:
+		if r.AssociatedData == "" {
+			return nil, fmt.Errorf("associated data is required to decrypt name bound secret: %v", r.URL)
+		}
+		return []byte(r.AssociatedData), nil
+	case BindingURL:
+		if r.AssociatedData != "" {
+			return []byte(r.AssociatedData), nil
+		}
+		return []byte(url.Normalize(expandHome(r.URL), file.Scheme)), nil
+	}
+	return nil, fmt.Errorf("unsupported associated data binding: %v", binding)
+}
+
 // Validate checks if resource if valid
 func (r *Resource) Validate() error {
 	if r == nil {
@@ -6,8 +6,15 @@ import (
 	_ "github.com/viant/afsc/gs"
 	_ "github.com/viant/afsc/s3"
 	"github.com/viant/scy/cmd"
+	_ "github.com/viant/scy/kms/aes"
+	_ "github.com/viant/scy/kms/age"
+	_ "github.com/viant/scy/kms/aws"
 	_ "github.com/viant/scy/kms/blowfish"
 	_ "github.com/viant/scy/kms/gcp"
+	_ "github.com/viant/scy/kms/plugin"
+	_ "github.com/viant/scy/kms/remote"
+	_ "github.com/viant/scy/kms/vault"
+	_ "github.com/viant/scy/vault/kv"
 	"os"
 )
 
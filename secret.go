@@ -3,6 +3,7 @@ package scy
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/viant/scy/kms"
 	"github.com/viant/toolbox/data"
 	"net/url"
 )
@@ -10,9 +11,11 @@ import (
 // Secret represent secret
 type Secret struct {
 	*Resource
-	Target  interface{}
-	payload []byte
-	IsPlain bool
+	Target     interface{}
+	Header     *kms.Header //ciphertext header, nil for plain or legacy payload
+	MatchedKey string      //key that decrypted the secret, for a keyring the matching keyring key
+	payload    []byte
+	IsPlain    bool
 }
 
 // Validate checks if secret is valid
@@ -28,13 +31,30 @@ func (s *Secret) String() string {
 	return string(s.payload)
 }
 
-// Decode secret into target
+// Decode secret into target, dotenv and INI payloads are decoded like JSON objects
 func (s *Secret) Decode(target interface{}) error {
+	if s.Resource != nil {
+		if format := Format(s.URL); isTextDocument(format, s.payload) {
+			return unmarshalText(s.payload, format == FormatINI, target)
+		}
+	}
 	return json.Unmarshal(s.payload, target)
 }
 
+// WithDocument sets dotenv or INI document target values are stored into, its comments, ordering and other keys are preserved
+func (s *Secret) WithDocument(document []byte) *Secret {
+	s.payload = document
+	return s
+}
+
 // Expand expands text with secret data
 func (s *Secret) Expand(text string) string {
+	replacement := s.expansionMap()
+	return replacement.ExpandAsText(text)
+}
+
+// expansionMap returns secret data map, a named secret is nested under its name
+func (s *Secret) expansionMap() data.Map {
 	var replacement = data.NewMap()
 	if s.IsPlain {
 		replacement[s.Name] = string(s.payload)
@@ -51,7 +71,7 @@ func (s *Secret) Expand(text string) string {
 	}
 	replacement["resourceURL"] = s.Resource.URL
 	replacement["resourceURLEnc"] = url.QueryEscape(s.Resource.URL)
-	return replacement.ExpandAsText(text)
+	return replacement
 }
 
 // NewSecret creates a secret
@@ -9,6 +9,7 @@ import (
 	"github.com/viant/afs"
 	"github.com/viant/afs/file"
 	"github.com/viant/afs/storage"
+	"github.com/viant/afs/url"
 	"github.com/viant/scy/cred"
 	"github.com/viant/scy/kms"
 	"gopkg.in/yaml.v3"
@@ -22,7 +23,25 @@ const inlineBase64Prefix = "inlined://base64/"
 
 // Service represents secret service
 type Service struct {
-	fs afs.Service
+	fs          afs.Service
+	cache       *secretCache
+	trustedKeys map[string]bool
+}
+
+// untrustedHeaderKinds represents key kinds never taken from a ciphertext header, their material is publicly known or host local
+var untrustedHeaderKinds = map[string]bool{"default": true, "mac": true, "raw": true, "inline": true, "localhost": true}
+
+// WithTrustedKeys allows keys recorded in ciphertext headers to decrypt secrets, by default only Resource.Key does,
+// default, mac, raw, inline and localhost (file) keys and unregistered schemes are never taken from a header
+func WithTrustedKeys(keys ...string) Option {
+	return func(s *Service) {
+		if s.trustedKeys == nil {
+			s.trustedKeys = map[string]bool{}
+		}
+		for _, key := range keys {
+			s.trustedKeys[key] = true
+		}
+	}
 }
 
 // Store stores secret
@@ -31,8 +50,16 @@ func (s *Service) Store(ctx context.Context, secret *Secret) error {
 	if err != nil {
 		return err
 	}
+	if secret.Version != "" {
+		return fmt.Errorf("can not store pinned version %v: %v", secret.Version, secret.URL)
+	}
 	payload := secret.payload
-	err = s.store(ctx, secret, err, payload)
+	if s.cache != nil {
+		defer s.cache.invalidate(secret.URL)
+	}
+	if err = s.store(ctx, secret, err, payload); err == nil {
+		return s.recordGeneration(ctx, secret.Resource)
+	}
 	if err != nil {
 		if secret.Resource.Fallback != nil {
 			clone := *secret
@@ -49,8 +76,13 @@ func (s *Service) store(ctx context.Context, secret *Secret, err error, payload
 		return err
 	}
 	shallCipher := key != nil
+	ctx, binding := s.bind(ctx, secret.Resource, key, cipher)
 	if secret.Target != nil {
-		if securable, ok := secret.Target.(kms.Securable); ok {
+		securable, ok := secret.Target.(kms.Securable)
+		if !ok {
+			securable, ok = kms.TaggedSecurable(secret.Target)
+		}
+		if ok {
 			if key == nil {
 				return fmt.Errorf("enc key is required by target: %T", secret.Target)
 			}
@@ -60,7 +92,9 @@ func (s *Service) store(ctx context.Context, secret *Secret, err error, payload
 			}
 		}
 		ext := strings.ToLower(filepath.Ext(secret.URL))
-		if ext == ".yml" || ext == ".yaml" {
+		if format := Format(secret.URL); format == FormatEnv || format == FormatINI {
+			payload, err = marshalText(secret.payload, format == FormatINI, secret.Target) //loaded secret payload keeps comments and ordering
+		} else if ext == ".yml" || ext == ".yaml" {
 			payload, err = yaml.Marshal(secret.Target)
 		} else {
 			payload, err = json.Marshal(secret.Target)
@@ -69,17 +103,19 @@ func (s *Service) store(ctx context.Context, secret *Secret, err error, payload
 			return err
 		}
 	}
-	if shallCipher {
-		if payload, err = cipher.Encrypt(ctx, key, payload); err != nil {
-			return err
-		}
-	}
-
 	var options []storage.Option
 	if secret.Resource != nil && len(secret.Resource.Options) > 0 {
 		options = secret.Resource.Options
 	}
-	return s.fs.Upload(ctx, secret.URL, file.DefaultFileOsMode, bytes.NewReader(payload), options...)
+	if !shallCipher {
+		return s.fs.Upload(ctx, secret.URL, file.DefaultFileOsMode, bytes.NewReader(payload), options...)
+	}
+	reader, err := s.encrypt(ctx, key, cipher, binding, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return s.fs.Upload(ctx, secret.URL, file.DefaultFileOsMode, reader, options...)
 }
 
 func (s *Service) loadKeyCipher(resourceKey string) (*kms.Key, kms.Cipher, error) {
@@ -97,76 +133,143 @@ func (s *Service) loadKeyCipher(resourceKey string) (*kms.Key, kms.Cipher, error
 	return key, cipher, nil
 }
 
-// Load loads secret
+// bind returns context with resource associated data when the cipher authenticates it, and binding kind recorded in ciphertext header
+func (s *Service) bind(ctx context.Context, resource *Resource, key *kms.Key, cipher kms.Cipher) (context.Context, string) {
+	if key == nil || resource == nil {
+		return ctx, ""
+	}
+	if primary := key.Primary(); primary != key {
+		if primaryCipher, err := kms.Lookup(primary.Scheme); err == nil {
+			cipher = primaryCipher
+		}
+	}
+	binding, data := resource.binding()
+	if binding == "" || !kms.BindsAssociatedData(cipher) {
+		return ctx, ""
+	}
+	return kms.WithAssociatedData(ctx, data), binding
+}
+
+// resolveKeyCipher resolves key and cipher, resource key takes precedence, a key recorded in the ciphertext header
+// is only used without resource key when it is trusted (see WithTrustedKeys); the header itself is not authenticated.
+// An unauthenticated cipher (i.e. blowfish) decrypts with a wrong key without error, thus when the header names another key,
+// the trusted header key is used instead, or an error is returned
+func (s *Service) resolveKeyCipher(resourceKey string, header *kms.Header) (*kms.Key, kms.Cipher, error) {
+	headerKey := s.headerKey(resourceKey, header)
+	if resourceKey == "" && headerKey != "" {
+		return s.loadKeyCipher(headerKey)
+	}
+	key, cipher, err := s.loadKeyCipher(resourceKey)
+	if err != nil || key == nil || header == nil || header.KeyID == "" || key.ID() == "" || key.HasID(header.KeyID) || kms.Authenticates(key) {
+		return key, cipher, err
+	}
+	if headerKey != "" {
+		return s.loadKeyCipher(headerKey)
+	}
+	return nil, nil, fmt.Errorf("ciphertext was encrypted with key %v, not %v", header.KeyID, key.ID())
+}
+
+// headerKey returns trusted key recorded in the ciphertext header other than resource key, or empty string
+func (s *Service) headerKey(resourceKey string, header *kms.Header) string {
+	if header == nil || header.KeyID == "" || header.KeyID == resourceKey || !s.trustedKeys[header.KeyID] {
+		return ""
+	}
+	if !kms.Registered(kms.KeyScheme(header.KeyID)) || untrustedHeaderKinds[url.Host(header.KeyID)] {
+		return ""
+	}
+	return header.KeyID
+}
+
+// Load loads secret, with cache enabled (see WithCache) cached secret is returned
 func (s *Service) Load(ctx context.Context, resource *Resource) (*Secret, error) {
+	if s.cache != nil {
+		if key := resource.cacheKey(); key != "" {
+			clone := *resource //background reload must not share caller resource
+			return s.cache.get(ctx, key, expandHome(resource.URL), func(ctx context.Context) (*Secret, error) {
+				return s.loadResource(ctx, &clone)
+			})
+		}
+	}
+	return s.loadResource(ctx, resource)
+}
+
+// InvalidateCache removes cached secrets for supplied resource URLs, all cached secrets without URLs
+func (s *Service) InvalidateCache(URLs ...string) {
+	if s.cache != nil {
+		s.cache.invalidate(URLs...)
+	}
+}
+
+func (s *Service) loadResource(ctx context.Context, resource *Resource) (*Secret, error) {
 	data := resource.Data
 	secret, err := s.load(ctx, resource, data)
 	if err != nil {
 		if resource.Fallback != nil {
-			return s.Load(ctx, resource.Fallback)
+			return s.loadResource(ctx, resource.Fallback)
 		}
 	}
 	return secret, err
 }
 
 func (s *Service) load(ctx context.Context, resource *Resource, data []byte) (*Secret, error) {
-	if len(resource.Data) == 0 {
-		if strings.HasPrefix(resource.URL, "~") {
-			resource.URL = os.Getenv("HOME") + resource.URL[1:]
-		} else if strings.HasPrefix(resource.URL, "/~") {
-			resource.URL = os.Getenv("HOME") + resource.URL[2:]
-		}
-		if inlinePayload, ok, err := decodeInlineBase64(resource.URL); ok {
-			if err != nil {
-				return nil, err
-			}
-			data = inlinePayload
-		} else {
-
-			resource.Init()
-			for i := 0; i < resource.MaxRetry; i++ {
-				tCtx, cancel := context.WithTimeout(ctx, resource.Timeout())
-				data, err = s.fs.DownloadWithURL(tCtx, resource.URL, resource.Options...)
-				cancel()
-				if err == nil {
-					break
-				}
-			}
-			if err != nil {
-				return nil, err
-			}
-		}
+	data, err := s.download(ctx, resource, data)
+	if err != nil {
+		return nil, err
 	}
-	key, cipher, err := s.loadKeyCipher(resource.Key)
+	header, data, err := kms.DecodeHeader(data)
 	if err != nil {
 		return nil, err
 	}
+	key, cipher, err := s.resolveKeyCipher(resource.Key, header)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil && key == nil {
+		return nil, fmt.Errorf("key is required to decrypt %v payload: %v", header.Algorithm, resource.URL)
+	}
 	secret := &Secret{
 		Resource: resource,
+		Header:   header,
 		payload:  data,
 	}
+	boundCtx, binding := s.bind(ctx, resource, key, cipher)
+	if header != nil {
+		associatedData, err := resource.associatedData(header.Binding)
+		if err != nil {
+			return nil, err
+		}
+		ctx = kms.WithAssociatedData(ctx, associatedData)
+	}
 	ext := strings.ToLower(filepath.Ext(resource.URL))
 	isYAML := ext == ".yml" || ext == ".yaml"
 	isJSON := isJson(data)
+	textFormat := Format(resource.URL)
+	isText := isTextDocument(textFormat, data)
 
 	if resource.Name == "" && resource.target == nil {
-		if isJSON || isYAML {
+		if isJSON || isYAML || isText {
 			resource.target = reflect.TypeOf(cred.Generic{})
 		}
 	}
 
 	shallDecipher := key != nil
-	if resource.target != nil && (isJSON || isYAML) {
+	if resource.target != nil && header == nil && (isJSON || isYAML || isText) {
 		value := reflect.New(resource.target).Interface()
 		if isYAML {
 			err = yaml.Unmarshal(data, value)
+		} else if isText {
+			err = unmarshalText(data, textFormat == FormatINI, value)
 		} else {
 			err = json.Unmarshal(data, value)
 		}
 		if err != nil {
 			return nil, err
 		}
-		if securable, ok := value.(kms.Securable); ok {
+		securable, ok := value.(kms.Securable)
+		if !ok {
+			securable, ok = kms.TaggedSecurable(value)
+		}
+		if ok {
 			_, isGeneric := value.(*cred.Generic)
 			shallDecipher = false
 			if key == nil {
@@ -174,19 +277,26 @@ func (s *Service) load(ctx context.Context, resource *Resource, data []byte) (*S
 					return nil, fmt.Errorf("key is required by type %T: %v", value, resource.URL)
 				}
 			} else {
-				if err = securable.Decipher(ctx, key); err != nil {
+				if err = securable.Decipher(boundCtx, key); err != nil && binding != "" {
+					err = securable.Decipher(ctx, key) //unbound or legacy document
+				}
+				if err != nil {
 					return nil, err
 				}
+				secret.MatchedKey = key.Matched().ID()
 			}
 		}
 		secret.Target = value
 	}
 	if shallDecipher {
-		if data, err = cipher.Decrypt(ctx, key, data); err != nil {
+		var matched *kms.Key
+		if data, matched, err = s.decrypt(ctx, s.headerKey(resource.Key, header), key, cipher, data); err != nil {
 			return nil, err
 		}
+		secret.MatchedKey = matched.ID()
 		// re-evaluate JSON and YAML after decryption
 		isJSON = isJson(data)
+		isText = isTextDocument(textFormat, data)
 		// YAML detection relies on extension
 		if resource.target != nil {
 			value := reflect.New(resource.target).Interface()
@@ -194,6 +304,10 @@ func (s *Service) load(ctx context.Context, resource *Resource, data []byte) (*S
 				if err = yaml.Unmarshal(data, value); err == nil {
 					secret.Target = value
 				}
+			} else if isText {
+				if err = unmarshalText(data, textFormat == FormatINI, value); err == nil {
+					secret.Target = value
+				}
 			} else if isJSON {
 				if err = json.Unmarshal(data, value); err == nil {
 					secret.Target = value
@@ -202,13 +316,15 @@ func (s *Service) load(ctx context.Context, resource *Resource, data []byte) (*S
 		}
 	}
 
-	secret.IsPlain = !(isJSON || isYAML)
+	secret.IsPlain = !(isJSON || isYAML || isText)
 	secret.payload = data
 	if secret.Target == nil {
 		secret.Target = string(data)
 	} else {
 		if isYAML {
 			secret.payload, _ = yaml.Marshal(secret.Target)
+		} else if isText { //original document keeps comments and ordering
+			secret.payload, _ = marshalText(data, textFormat == FormatINI, secret.Target)
 		} else {
 			secret.payload, _ = json.Marshal(secret.Target)
 		}
@@ -216,6 +332,58 @@ func (s *Service) load(ctx context.Context, resource *Resource, data []byte) (*S
 	return secret, nil
 }
 
+// download returns resource payload, supplied data (resource inline or previously downloaded data) is returned as is
+func (s *Service) download(ctx context.Context, resource *Resource, data []byte) ([]byte, error) {
+	if len(data) > 0 {
+		return data, nil
+	}
+	resource.URL = expandHome(resource.URL)
+	if inlinePayload, ok, err := decodeInlineBase64(resource.URL); ok {
+		return inlinePayload, err
+	}
+	URL := resource.URL
+	if resource.Version != "" {
+		URL = versionURL(URL, resource.Version)
+	}
+	var err error
+	resource.Init()
+	for i := 0; i < resource.MaxRetry; i++ {
+		tCtx, cancel := context.WithTimeout(ctx, resource.Timeout())
+		data, err = s.fs.DownloadWithURL(tCtx, URL, resource.Options...)
+		cancel()
+		if err == nil {
+			break
+		}
+	}
+	return data, err
+}
+
+// decrypt decrypts data, when the key fails, fallback key (a trusted key recorded in the header, see headerKey) is tried,
+// it returns the key that decrypted data, for a keyring the matching keyring key
+func (s *Service) decrypt(ctx context.Context, fallback string, key *kms.Key, cipher kms.Cipher, data []byte) ([]byte, *kms.Key, error) {
+	result, err := cipher.Decrypt(ctx, key, data)
+	if err == nil || fallback == "" || fallback == key.Raw {
+		return result, key.Matched(), err
+	}
+	fallbackKey, fallbackCipher, fallbackErr := s.loadKeyCipher(fallback)
+	if fallbackErr != nil {
+		return nil, nil, err
+	}
+	if result, fallbackErr = fallbackCipher.Decrypt(ctx, fallbackKey, data); fallbackErr != nil {
+		return nil, nil, err
+	}
+	return result, fallbackKey.Matched(), nil
+}
+
+func expandHome(URL string) string {
+	if strings.HasPrefix(URL, "~") {
+		return os.Getenv("HOME") + URL[1:]
+	} else if strings.HasPrefix(URL, "/~") {
+		return os.Getenv("HOME") + URL[2:]
+	}
+	return URL
+}
+
 func decodeInlineBase64(rawURL string) ([]byte, bool, error) {
 	value := strings.TrimSpace(rawURL)
 	if !strings.HasPrefix(value, inlineBase64Prefix) {
@@ -238,6 +406,10 @@ func isJson(data []byte) bool {
 }
 
 // New creates a new secret service
-func New() *Service {
-	return &Service{fs: afs.New()}
+func New(options ...Option) *Service {
+	result := &Service{fs: afs.New()}
+	for _, option := range options {
+		option(result)
+	}
+	return result
 }
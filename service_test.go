@@ -3,10 +3,14 @@ package scy_test
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/viant/scy"
 	"github.com/viant/scy/cred"
+	"github.com/viant/scy/kms"
+	_ "github.com/viant/scy/kms/aes"
 	_ "github.com/viant/scy/kms/blowfish"
+	"github.com/viant/scy/kms/memory"
 	"os"
 	"path"
 	"testing"
@@ -98,3 +102,274 @@ func TestService_Load_InlinedBase64InvalidPayload(t *testing.T) {
 	}
 	assert.Contains(t, err.Error(), "invalid inlined base64 payload")
 }
+
+func TestService_Load_Header(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyHeaderTestKey", "header test key")
+
+	resource := scy.NewResource("key", "/tmp/header.sec", "blowfish://env/scyHeaderTestKey")
+	secret := scy.NewSecret("this is secret", resource)
+	if !assert.Nil(t, srv.Store(ctx, secret)) {
+		return
+	}
+	payload, err := os.ReadFile(resource.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	header, _, err := kms.DecodeHeader(payload)
+	if !assert.Nil(t, err) || !assert.NotNil(t, header) {
+		return
+	}
+	assert.EqualValues(t, &kms.Header{Version: kms.HeaderVersion, Scheme: "blowfish", KeyID: "blowfish://env/scyHeaderTestKey", Algorithm: "blowfish-cbc"}, header)
+
+	_, err = srv.Load(ctx, scy.NewResource("key", resource.URL, ""))
+	assert.NotNil(t, err, "untrusted header key")
+
+	_ = os.Setenv("scyHeaderOtherKey", "other header test key")
+	trusted := scy.New(scy.WithTrustedKeys("blowfish://env/scyHeaderTestKey"))
+	loaded, err := trusted.Load(ctx, scy.NewResource("key", resource.URL, ""))
+	if assert.Nil(t, err, "omitted key") {
+		assert.EqualValues(t, "this is secret", loaded.Target)
+		assert.EqualValues(t, header, loaded.Header)
+	}
+	loaded, err = trusted.Load(ctx, scy.NewResource("key", resource.URL, "aes://env/scyHeaderOtherKey"))
+	if assert.Nil(t, err, "different resource key") {
+		assert.EqualValues(t, "this is secret", loaded.Target)
+		assert.EqualValues(t, "blowfish://env/scyHeaderTestKey", loaded.MatchedKey)
+	}
+}
+
+func TestService_Load_UnauthenticatedHeader(t *testing.T) {
+	ctx := context.Background()
+	_ = os.Setenv("scyUnauthenticatedTestKey", "unauthenticated test key")
+	URL := path.Join(t.TempDir(), "unauthenticated.sec")
+	if !assert.Nil(t, scy.New().Store(ctx, scy.NewSecret("this is secret", scy.NewResource("key", URL, "blowfish://env/scyUnauthenticatedTestKey")))) {
+		return
+	}
+	_, err := scy.New().Load(ctx, scy.NewResource("key", URL, "blowfish://default"))
+	if assert.NotNil(t, err, "blowfish decrypts with a wrong key without error") {
+		assert.Contains(t, err.Error(), "blowfish://env/scyUnauthenticatedTestKey")
+	}
+	trusted := scy.New(scy.WithTrustedKeys("blowfish://env/scyUnauthenticatedTestKey"))
+	loaded, err := trusted.Load(ctx, scy.NewResource("key", URL, "blowfish://default"))
+	if assert.Nil(t, err, "trusted header key") {
+		assert.EqualValues(t, "this is secret", loaded.Target)
+		assert.EqualValues(t, "blowfish://env/scyUnauthenticatedTestKey", loaded.MatchedKey)
+	}
+}
+
+func TestService_Load_PlantedHeader(t *testing.T) {
+	ctx := context.Background()
+	_ = os.Setenv("scyPlantedTestKey", "planted test key material")
+	URL := path.Join(t.TempDir(), "planted.sec")
+	planted := scy.NewResource("key", URL, "blowfish://default")
+	if !assert.Nil(t, scy.New().Store(ctx, scy.NewSecret("forged", planted))) {
+		return
+	}
+	var testCases = []struct {
+		description string
+		srv         *scy.Service
+	}{
+		{description: "default service", srv: scy.New()},
+		{description: "default key is never trusted", srv: scy.New(scy.WithTrustedKeys("blowfish://default"))},
+	}
+	for _, testCase := range testCases {
+		_, err := testCase.srv.Load(ctx, scy.NewResource("key", URL, "aes://env/scyPlantedTestKey"))
+		assert.NotNil(t, err, testCase.description)
+		_, err = testCase.srv.Load(ctx, scy.NewResource("key", URL, ""))
+		assert.NotNil(t, err, testCase.description+": omitted key")
+	}
+}
+
+func TestService_Load_LegacyPayload(t *testing.T) {
+	ctx := context.Background()
+	key, _ := kms.NewKey("blowfish://default")
+	cipher, _ := kms.Lookup(key.Scheme)
+	encrypted, err := cipher.Encrypt(ctx, key, []byte("legacy secret"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	URL := "/tmp/legacy.sec"
+	if !assert.Nil(t, os.WriteFile(URL, encrypted, 0600)) {
+		return
+	}
+	loaded, err := scy.New().Load(ctx, scy.NewResource("key", URL, "blowfish://default"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, loaded.Header)
+	assert.EqualValues(t, "legacy secret", loaded.Target)
+}
+
+func TestService_Load_Keyring(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyKeyringTestKey", "keyring test key")
+	keyring := "keyring://aes://env/scyKeyringTestKey,blowfish://default"
+
+	legacyURL := "/tmp/keyring_legacy.json"
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(&cred.Basic{Username: "Bob", Password: "old"}, scy.NewResource("", legacyURL, "blowfish://default")))) {
+		return
+	}
+	rotatedURL := "/tmp/keyring_rotated.sec"
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("rotated secret", scy.NewResource("", rotatedURL, keyring)))) {
+		return
+	}
+
+	secret, err := srv.Load(ctx, scy.NewResource(cred.Basic{}, legacyURL, keyring))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "old", secret.Target.(*cred.Basic).Password)
+		assert.EqualValues(t, "blowfish://default", secret.MatchedKey)
+	}
+	secret, err = srv.Load(ctx, scy.NewResource("", rotatedURL, keyring))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "rotated secret", secret.Target)
+		assert.EqualValues(t, "aes://env/scyKeyringTestKey", secret.MatchedKey)
+	}
+	secret, err = srv.Load(ctx, scy.NewResource("", rotatedURL, "aes://env/scyKeyringTestKey"))
+	if assert.Nil(t, err, "primary key only") {
+		assert.EqualValues(t, "rotated secret", secret.Target)
+	}
+}
+
+func TestService_Load_Binding(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyBindingTestKey", "binding test key")
+	key := "aes://env/scyBindingTestKey"
+	originalURL, movedURL := "/tmp/binding_original.sec", "/tmp/binding_moved.sec"
+	move := func() {
+		data, err := os.ReadFile(originalURL)
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(movedURL, data, 0600))
+	}
+	defer os.Remove(originalURL)
+	defer os.Remove(movedURL)
+
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("bound secret", scy.NewResource("", originalURL, key)))) {
+		return
+	}
+	secret, err := srv.Load(ctx, scy.NewResource("", originalURL, key))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "bound secret", secret.Target)
+		assert.EqualValues(t, scy.BindingURL, secret.Header.Binding)
+	}
+	move()
+	_, err = srv.Load(ctx, scy.NewResource("", movedURL, key))
+	assert.NotNil(t, err, "moved bound secret")
+	moved := scy.NewResource("", movedURL, key)
+	moved.AssociatedData = "file://localhost" + originalURL
+	secret, err = srv.Load(ctx, moved)
+	if assert.Nil(t, err, "moved with original URL") {
+		assert.EqualValues(t, "bound secret", secret.Target)
+	}
+
+	unbound := scy.NewResource("", originalURL, key)
+	unbound.Unbound = true
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("unbound secret", unbound))) {
+		return
+	}
+	move()
+	secret, err = srv.Load(ctx, scy.NewResource("", movedURL, key))
+	if assert.Nil(t, err, "moved unbound secret") {
+		assert.EqualValues(t, "unbound secret", secret.Target)
+	}
+
+	named := scy.NewResource("", originalURL, key)
+	named.AssociatedData = "prod/db"
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("named secret", named))) {
+		return
+	}
+	move()
+	moved = scy.NewResource("", movedURL, key)
+	moved.AssociatedData = "prod/db"
+	secret, err = srv.Load(ctx, moved)
+	if assert.Nil(t, err, "moved name bound secret") {
+		assert.EqualValues(t, "named secret", secret.Target)
+	}
+	_, err = srv.Load(ctx, scy.NewResource("", movedURL, key))
+	assert.NotNil(t, err, "name bound secret without associated data")
+
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(&cred.Basic{Username: "Bob", Password: "pass"}, scy.NewResource("", originalURL, key)))) {
+		return
+	}
+	secret, err = srv.Load(ctx, scy.NewResource(cred.Basic{}, originalURL, key))
+	if assert.Nil(t, err, "bound fields") {
+		assert.EqualValues(t, "pass", secret.Target.(*cred.Basic).Password)
+	}
+	move()
+	_, err = srv.Load(ctx, scy.NewResource(cred.Basic{}, movedURL, key))
+	assert.NotNil(t, err, "moved bound fields")
+
+	unbound = scy.NewResource("", originalURL, key)
+	unbound.Unbound = true
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(&cred.Basic{Username: "Bob", Password: "pass"}, unbound))) {
+		return
+	}
+	move()
+	secret, err = srv.Load(ctx, scy.NewResource(cred.Basic{}, movedURL, key))
+	if assert.Nil(t, err, "moved unbound fields") {
+		assert.EqualValues(t, "pass", secret.Target.(*cred.Basic).Password)
+	}
+}
+
+func TestService_Store_Memory(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	URL := path.Join(t.TempDir(), "memory.json")
+	srv := scy.New()
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(&cred.Basic{Username: "bob", Password: "distinctive-password"}, scy.NewResource("", URL, cipher.Key("app"))))) {
+		return
+	}
+	data, err := os.ReadFile(URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, cipher.VerifyNoPlaintext(data))
+	assert.EqualValues(t, 1, cipher.Count(memory.Encrypt))
+
+	cipher.Fail(cipher.Key("app"), errors.New("kms unavailable"), memory.Decrypt)
+	_, err = srv.Load(ctx, scy.NewResource(cred.Basic{}, URL, cipher.Key("app")))
+	assert.NotNil(t, err)
+	cipher.Fail(cipher.Key("app"), nil)
+	secret, err := srv.Load(ctx, scy.NewResource(cred.Basic{}, URL, cipher.Key("app")))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "distinctive-password", secret.Target.(*cred.Basic).Password)
+	}
+}
+
+type taggedDatabase struct {
+	Host              string
+	Password          string            `json:",omitempty" scy:"encrypt,into=EncryptedPassword"`
+	EncryptedPassword string            `json:",omitempty"`
+	Replicas          []*taggedDatabase `json:",omitempty"`
+}
+
+func TestService_Store_Tagged(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("tagged")
+	URL := path.Join(t.TempDir(), "db.json")
+	srv := scy.New()
+	config := &taggedDatabase{Host: "primary", Password: "distinctive-primary", Replicas: []*taggedDatabase{{Host: "replica", Password: "distinctive-replica"}}}
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret(config, scy.NewResource(taggedDatabase{}, URL, key)))) {
+		return
+	}
+	data, err := os.ReadFile(URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, cipher.VerifyNoPlaintext(data))
+	assert.Contains(t, string(data), `"Host":"replica"`, "document is not encrypted as a whole")
+
+	secret, err := srv.Load(ctx, scy.NewResource(taggedDatabase{}, URL, key))
+	if assert.Nil(t, err) {
+		actual := secret.Target.(*taggedDatabase)
+		assert.EqualValues(t, "distinctive-primary", actual.Password)
+		assert.EqualValues(t, "distinctive-replica", actual.Replicas[0].Password)
+		assert.EqualValues(t, "", actual.Replicas[0].EncryptedPassword)
+	}
+	_, err = srv.Load(ctx, scy.NewResource(taggedDatabase{}, URL, ""))
+	assert.NotNil(t, err, "key is required")
+}
@@ -0,0 +1,166 @@
+package scy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/viant/afs/file"
+	"github.com/viant/scy/kms"
+	"io"
+)
+
+// StoreStream encrypts reader data into resource location, kms.StreamCipher ciphers encrypt chunk by chunk
+// without holding the whole payload in memory, other ciphers read the whole payload
+func (s *Service) StoreStream(ctx context.Context, resource *Resource, reader io.Reader) error {
+	if err := resource.Validate(); err != nil {
+		return err
+	}
+	if resource.Version != "" {
+		return fmt.Errorf("can not store pinned version %v: %v", resource.Version, resource.URL)
+	}
+	defer s.InvalidateCache(resource.URL)
+	key, cipher, err := s.loadKeyCipher(resource.Key)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		ctx, binding := s.bind(ctx, resource, key, cipher)
+		encrypted, err := s.encrypt(ctx, key, cipher, binding, reader)
+		if err != nil {
+			return err
+		}
+		defer encrypted.Close()
+		reader = encrypted
+	}
+	if err = s.fs.Upload(ctx, resource.URL, file.DefaultFileOsMode, reader, resource.Options...); err != nil {
+		return err
+	}
+	return s.recordGeneration(ctx, resource)
+}
+
+// LoadStream returns decrypted resource data reader, kms.StreamCipher ciphers decrypt chunk by chunk,
+// the caller is responsible for closing the reader
+func (s *Service) LoadStream(ctx context.Context, resource *Resource) (io.ReadCloser, error) {
+	reader, err := s.loadStream(ctx, resource)
+	if err != nil && resource.Fallback != nil {
+		return s.LoadStream(ctx, resource.Fallback)
+	}
+	return reader, err
+}
+
+func (s *Service) loadStream(ctx context.Context, resource *Resource) (io.ReadCloser, error) {
+	source, err := s.open(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+	header, reader, err := kms.DecodeHeaderReader(source)
+	if err != nil {
+		_ = source.Close()
+		return nil, err
+	}
+	key, cipher, err := s.resolveKeyCipher(resource.Key, header)
+	if err == nil && header != nil && key == nil {
+		err = fmt.Errorf("key is required to decrypt %v payload: %v", header.Algorithm, resource.URL)
+	}
+	if err != nil {
+		_ = source.Close()
+		return nil, err
+	}
+	if key == nil {
+		return &readCloser{Reader: reader, Closer: source}, nil
+	}
+	if header != nil {
+		associatedData, err := resource.associatedData(header.Binding)
+		if err != nil {
+			_ = source.Close()
+			return nil, err
+		}
+		ctx = kms.WithAssociatedData(ctx, associatedData)
+	}
+	if streamCipher, ok := cipher.(kms.StreamCipher); ok {
+		decrypted, err := streamCipher.DecryptReader(ctx, key, reader)
+		if err != nil {
+			_ = source.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: decrypted, Closer: source}, nil
+	}
+	data, err := io.ReadAll(reader)
+	_ = source.Close()
+	if err != nil {
+		return nil, err
+	}
+	if data, _, err = s.decrypt(ctx, s.headerKey(resource.Key, header), key, cipher, data); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// encrypt returns reader of ciphertext header followed by encrypted data, the reader has to be closed
+func (s *Service) encrypt(ctx context.Context, key *kms.Key, cipher kms.Cipher, binding string, reader io.Reader) (io.ReadCloser, error) {
+	header := kms.NewHeader(key, cipher)
+	header.Binding = binding
+	encodedHeader, err := header.Encode(nil)
+	if err != nil {
+		return nil, err
+	}
+	streamCipher, ok := cipher.(kms.StreamCipher)
+	if !ok {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = cipher.Encrypt(ctx, key, data); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(io.MultiReader(bytes.NewReader(encodedHeader), bytes.NewReader(data))), nil
+	}
+	pipeReader, pipeWriter := io.Pipe()
+	buffered := bufio.NewWriterSize(pipeWriter, kms.DefaultChunkSize)
+	_, _ = buffered.Write(encodedHeader)
+	writer, err := streamCipher.EncryptWriter(ctx, key, buffered) //key errors are reported before upload starts
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		_, err := io.Copy(writer, reader)
+		if err == nil {
+			err = writer.Close()
+		}
+		if err == nil {
+			err = buffered.Flush()
+		}
+		_ = pipeWriter.CloseWithError(err)
+	}()
+	return pipeReader, nil
+}
+
+// open opens resource data reader
+func (s *Service) open(ctx context.Context, resource *Resource) (io.ReadCloser, error) {
+	if len(resource.Data) > 0 {
+		return io.NopCloser(bytes.NewReader(resource.Data)), nil
+	}
+	resource.URL = expandHome(resource.URL)
+	if inlinePayload, ok, err := decodeInlineBase64(resource.URL); ok {
+		return io.NopCloser(bytes.NewReader(inlinePayload)), err
+	}
+	URL := resource.URL
+	if resource.Version != "" {
+		URL = versionURL(URL, resource.Version)
+	}
+	resource.Init()
+	var reader io.ReadCloser
+	var err error
+	for i := 0; i < resource.MaxRetry; i++ {
+		if reader, err = s.fs.OpenURL(ctx, URL, resource.Options...); err == nil {
+			break
+		}
+	}
+	return reader, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
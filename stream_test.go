@@ -0,0 +1,79 @@
+package scy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/kms"
+	"io"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestService_StoreStream(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyStreamTestKey", "stream test key")
+	data := make([]byte, 3*kms.DefaultChunkSize+123)
+	_, _ = rand.Read(data)
+
+	var testCases = []struct {
+		description string
+		key         string
+		streamed    bool
+	}{
+		{description: "stream cipher", key: "aes://env/scyStreamTestKey", streamed: true},
+		{description: "block cipher", key: "blowfish://env/scyStreamTestKey"},
+		{description: "plain", key: ""},
+	}
+	for _, testCase := range testCases {
+		URL := path.Join(os.TempDir(), "scy_stream.bin")
+		payload := data
+		if testCase.key == "blowfish://env/scyStreamTestKey" {
+			payload = bytes.Repeat([]byte("blowfish drops zero bytes "), 100)
+		}
+		resource := scy.NewResource("", URL, testCase.key)
+		if !assert.Nil(t, srv.StoreStream(ctx, resource, bytes.NewReader(payload)), testCase.description) {
+			continue
+		}
+		stored, _ := os.ReadFile(URL)
+		header, body, err := kms.DecodeHeader(stored)
+		assert.Nil(t, err, testCase.description)
+		assert.EqualValues(t, testCase.key != "", header != nil, testCase.description)
+		assert.EqualValues(t, testCase.streamed, kms.IsStream(body), testCase.description)
+
+		reader, err := srv.LoadStream(ctx, scy.NewResource("", URL, testCase.key))
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		actual, err := io.ReadAll(reader)
+		assert.Nil(t, err, testCase.description)
+		assert.Nil(t, reader.Close(), testCase.description)
+		assert.EqualValues(t, payload, actual, testCase.description)
+		_ = os.Remove(URL)
+	}
+}
+
+func TestService_Store_StreamCipher(t *testing.T) {
+	ctx := context.Background()
+	srv := scy.New()
+	_ = os.Setenv("scyStreamTestKey", "stream test key")
+	URL := path.Join(os.TempDir(), "scy_stream.sec")
+	defer os.Remove(URL)
+	resource := scy.NewResource("", URL, "aes://env/scyStreamTestKey")
+	if !assert.Nil(t, srv.Store(ctx, scy.NewSecret("streamed secret", resource))) {
+		return
+	}
+	secret, err := srv.Load(ctx, scy.NewResource("", URL, "aes://env/scyStreamTestKey"))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "streamed secret", secret.Target)
+	}
+	_ = os.Setenv("scyStreamTestKey", "")
+	assert.NotNil(t, srv.Store(ctx, scy.NewSecret("streamed secret", scy.NewResource("", URL, "aes://env/scyStreamTestKey"))), "missing key")
+	stored, _ := os.ReadFile(URL)
+	assert.True(t, len(stored) > 0, "failed store should not truncate existing secret")
+	_ = os.Setenv("scyStreamTestKey", "stream test key")
+}
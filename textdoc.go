@@ -0,0 +1,357 @@
+package scy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// textDocument represents dotenv or INI document, unchanged lines (comments, blank lines, formatting) are rendered as is
+type textDocument struct {
+	ini   bool
+	lines []*textLine
+}
+
+type textLine struct {
+	raw       string
+	section   string
+	entry     bool
+	header    bool
+	prefix    string //indentation and export keyword
+	key       string
+	separator string
+	value     string
+	quote     byte
+	comment   string
+	updated   bool
+}
+
+// parseTextDocument parses dotenv or INI document, lines other than comments, blank lines, sections and key=value fail
+func parseTextDocument(data []byte, ini bool) (*textDocument, error) {
+	doc := &textDocument{ini: ini}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if text == "" {
+		return doc, nil
+	}
+	section := ""
+	for i, raw := range strings.Split(text, "\n") {
+		line := &textLine{raw: raw, section: section}
+		trimmed := strings.TrimSpace(raw)
+		switch {
+		case trimmed == "" || trimmed[0] == '#' || (ini && trimmed[0] == ';'):
+		case ini && trimmed[0] == '[':
+			if !strings.HasSuffix(trimmed, "]") {
+				return nil, fmt.Errorf("invalid section at line %v: %v", i+1, trimmed)
+			}
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			line.section = section
+			line.header = true
+		default:
+			if err := line.parseEntry(ini); err != nil {
+				return nil, fmt.Errorf("invalid line %v: %w", i+1, err)
+			}
+		}
+		doc.lines = append(doc.lines, line)
+	}
+	return doc, nil
+}
+
+func (l *textLine) parseEntry(ini bool) error {
+	index := strings.IndexByte(l.raw, '=')
+	if index == -1 {
+		return fmt.Errorf("expected key=value")
+	}
+	keyPart, valuePart := l.raw[:index], l.raw[index+1:]
+	l.entry = true
+	l.prefix = keyPart[:len(keyPart)-len(strings.TrimLeft(keyPart, " \t"))]
+	l.key = strings.TrimSpace(keyPart)
+	if !ini && strings.HasPrefix(l.key, "export ") {
+		l.prefix += "export "
+		l.key = strings.TrimSpace(l.key[len("export "):])
+	}
+	if l.key == "" || (!ini && strings.ContainsAny(l.key, " \t\"'")) {
+		return fmt.Errorf("invalid key: %q", l.key)
+	}
+	l.separator = keyPart[len(strings.TrimRight(keyPart, " \t")):] + "=" + valuePart[:len(valuePart)-len(strings.TrimLeft(valuePart, " \t"))]
+	value := strings.TrimSpace(valuePart)
+	rest := ""
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		l.quote = value[0]
+		end := closingQuote(value, l.quote)
+		if end == -1 {
+			return fmt.Errorf("unterminated quoted value of %v", l.key)
+		}
+		l.value, rest = value[1:end], strings.TrimSpace(value[end+1:])
+		if l.quote == '"' {
+			l.value = unescapeValue(l.value)
+		}
+	} else {
+		l.value = value
+		for _, marker := range []string{" #", "\t#", " ;", "\t;"} {
+			if index := strings.Index(l.value, marker); index != -1 && (marker[1] == '#' || ini) {
+				l.value, rest = strings.TrimSpace(l.value[:index]), strings.TrimSpace(l.value[index:])
+			}
+		}
+	}
+	if rest != "" && rest[0] != '#' && !(ini && rest[0] == ';') {
+		return fmt.Errorf("unexpected text after value of %v: %v", l.key, rest)
+	}
+	l.comment = rest
+	return nil
+}
+
+func closingQuote(value string, quote byte) int {
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && quote == '"':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+var valueUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+var valueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func unescapeValue(value string) string {
+	return valueUnescaper.Replace(value)
+}
+
+// set updates entry value, the line is rendered again
+func (l *textLine) set(value string) {
+	if l.value == value {
+		return
+	}
+	l.value = value
+	l.updated = true
+}
+
+// rename updates entry key, the line is rendered again
+func (l *textLine) rename(key string) {
+	l.key = key
+	l.updated = true
+}
+
+func (l *textLine) String() string {
+	if !l.updated {
+		return l.raw
+	}
+	value := l.value
+	switch {
+	case l.quote == '\'' && !strings.ContainsAny(value, "'\n"):
+		value = "'" + value + "'"
+	case l.quote == '"' || value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;\"'\\\n\r\t"):
+		value = `"` + valueEscaper.Replace(value) + `"`
+	}
+	result := l.prefix + l.key + l.separator + value
+	if l.comment != "" {
+		result += " " + l.comment
+	}
+	return result
+}
+
+// Bytes returns document text
+func (d *textDocument) Bytes() []byte {
+	var builder strings.Builder
+	for _, line := range d.lines {
+		builder.WriteString(line.String())
+		builder.WriteByte('\n')
+	}
+	return []byte(builder.String())
+}
+
+// Map returns document values, INI sections are nested maps, a top-level key named like a section is an error
+func (d *textDocument) Map() (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, line := range d.lines {
+		if !line.entry {
+			continue
+		}
+		if line.section == "" {
+			if _, ok := result[line.key].(map[string]interface{}); ok {
+				return nil, fmt.Errorf("key %v conflicts with section [%v]", line.key, line.key)
+			}
+			result[line.key] = line.value
+			continue
+		}
+		section, ok := result[line.section].(map[string]interface{})
+		if !ok {
+			if _, exists := result[line.section]; exists {
+				return nil, fmt.Errorf("key %v conflicts with section [%v]", line.section, line.section)
+			}
+			section = map[string]interface{}{}
+			result[line.section] = section
+		}
+		section[line.key] = line.value
+	}
+	return result, nil
+}
+
+// Apply updates document with values, keys are matched case-insensitively, Encrypted<Key> replaces <Key> in place
+// and the other way round, new keys with values are appended, an explicitly present empty value clears the entry,
+// keys missing in values (or null) leave the document intact
+func (d *textDocument) Apply(values map[string]interface{}) {
+	sections := map[string]map[string]interface{}{"": {}}
+	for key, value := range values {
+		if section, ok := value.(map[string]interface{}); ok && d.ini {
+			sections[key] = section
+			continue
+		}
+		sections[""][key] = value
+	}
+	for _, name := range sortedKeys(sections) {
+		d.applySection(name, sections[name])
+	}
+}
+
+func (d *textDocument) applySection(section string, values map[string]interface{}) {
+	handled := map[string]bool{}
+	for _, line := range d.lines {
+		if !line.entry || line.section != section {
+			continue
+		}
+		key, value, ok := lookupValue(values, line.key)
+		if ok && value != "" {
+			handled[key] = true
+			line.set(value)
+			continue
+		}
+		if applyRenamed(line, values, handled) {
+			continue
+		}
+		if ok { //explicitly present empty value clears the entry
+			handled[key] = true
+			line.set("")
+		}
+	}
+	var added []*textLine
+	for _, key := range sortedKeys(values) {
+		value := textValue(values[key])
+		if handled[key] || value == "" {
+			continue
+		}
+		if _, _, ok := d.lookupLine(section, key); ok {
+			continue
+		}
+		separator := "="
+		if d.ini {
+			separator = " = "
+		}
+		added = append(added, &textLine{section: section, entry: true, key: key, separator: separator, value: value, updated: true})
+	}
+	if len(added) == 0 {
+		return
+	}
+	d.insert(section, added)
+}
+
+// applyRenamed sets Encrypted<Key> line with deciphered <Key> value and <Key> line with Encrypted<Key> ciphertext
+func applyRenamed(line *textLine, values map[string]interface{}, handled map[string]bool) bool {
+	if strings.HasPrefix(strings.ToLower(line.key), encryptedFieldPrefix) { //deciphered value replaces ciphertext
+		plainKey := line.key[len(encryptedFieldPrefix):]
+		key, value, ok := lookupValue(values, plainKey)
+		if !ok || value == "" {
+			return false
+		}
+		handled[key] = true
+		line.rename(plainKey)
+		line.set(value)
+		return true
+	}
+	key, value, ok := lookupValue(values, encryptedFieldPrefix+line.key)
+	if !ok || value == "" {
+		return false
+	}
+	handled[key] = true //ciphertext replaces value
+	prefix := "Encrypted"
+	if line.key == strings.ToUpper(line.key) {
+		prefix = strings.ToUpper(prefix)
+	}
+	line.rename(prefix + line.key)
+	line.set(value)
+	return true
+}
+
+// insert inserts lines at the end of section, a missing section is appended
+func (d *textDocument) insert(section string, lines []*textLine) {
+	index := -1
+	for i, line := range d.lines {
+		if line.section == section && (line.entry || line.header) {
+			index = i
+		}
+		if section == "" && line.header {
+			break
+		}
+	}
+	if index == -1 && section == "" {
+		for index = 0; index < len(d.lines) && !d.lines[index].header; index++ {
+		}
+		index--
+	}
+	if index == -1 && section != "" {
+		header := &textLine{raw: "[" + section + "]", section: section, header: true}
+		d.lines = append(d.lines, header)
+		index = len(d.lines) - 1
+	}
+	var result = make([]*textLine, 0, len(d.lines)+len(lines))
+	result = append(result, d.lines[:index+1]...)
+	result = append(result, lines...)
+	d.lines = append(result, d.lines[index+1:]...)
+}
+
+func (d *textDocument) lookupLine(section, key string) (*textLine, int, bool) {
+	for i, line := range d.lines {
+		if line.entry && line.section == section && strings.EqualFold(line.key, key) {
+			return line, i, true
+		}
+	}
+	return nil, -1, false
+}
+
+// lookupValue returns values key and text value matching key, exact match is preferred, null values are ignored
+func lookupValue(values map[string]interface{}, key string) (string, string, bool) {
+	if value, ok := values[key]; ok && value != nil {
+		return key, textValue(value), true
+	}
+	for candidate, value := range values {
+		if value != nil && strings.EqualFold(candidate, key) {
+			return candidate, textValue(value), true
+		}
+	}
+	return "", "", false
+}
+
+// textValue returns value text, maps and slices are JSON encoded
+func textValue(value interface{}) string {
+	switch actual := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return actual
+	case map[string]interface{}:
+		if len(actual) == 0 {
+			return ""
+		}
+		encoded, _ := json.Marshal(actual)
+		return string(encoded)
+	case []interface{}:
+		if len(actual) == 0 {
+			return ""
+		}
+		encoded, _ := json.Marshal(actual)
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
+
+func sortedKeys[T any](aMap map[string]T) []string {
+	var result = make([]string, 0, len(aMap))
+	for key := range aMap {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
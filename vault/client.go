@@ -0,0 +1,180 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/viant/scy"
+	"github.com/viant/scy/cred"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// tokenKey represents cred.SecretKey.Key value for token authentication
+const tokenKey = "token"
+
+// ErrNotFound represents vault not found error
+var ErrNotFound = fmt.Errorf("vault: not found")
+
+// Client represents vault HTTP API client
+type Client struct {
+	config     *Config
+	secretKey  *cred.SecretKey
+	httpClient *http.Client
+	token      string
+	mux        sync.Mutex
+}
+
+// Response represents vault API response
+type Response struct {
+	Data   json.RawMessage `json:"data"`
+	Auth   *Auth           `json:"auth"`
+	Errors []string        `json:"errors"`
+}
+
+// Auth represents vault auth response
+type Auth struct {
+	ClientToken string `json:"client_token"`
+}
+
+// Do sends request to vault API path i.e. transit/encrypt/my-key, decodes response data into supplied output
+func (c *Client) Do(ctx context.Context, method, path string, input, output interface{}) error {
+	token, err := c.authToken(ctx, false)
+	if err != nil {
+		return err
+	}
+	status, err := c.do(ctx, method, path, token, input, output)
+	if status == http.StatusForbidden && c.isAppRole() {
+		if token, err = c.authToken(ctx, true); err != nil {
+			return err
+		}
+		_, err = c.do(ctx, method, path, token, input, output)
+	}
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method, path, token string, input, output interface{}) (int, error) {
+	var body io.Reader
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(data)
+	}
+	URL := strings.TrimRight(c.config.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	request, err := http.NewRequestWithContext(ctx, method, URL, body)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("X-Vault-Token", token)
+	}
+	if c.config.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", c.config.Namespace)
+	}
+	httpResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("vault: failed to call %v %v: %w", method, path, err)
+	}
+	defer httpResponse.Body.Close()
+	data, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return httpResponse.StatusCode, err
+	}
+	if httpResponse.StatusCode == http.StatusNotFound {
+		return httpResponse.StatusCode, fmt.Errorf("%w: %v", ErrNotFound, path)
+	}
+	response := &Response{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err = json.Unmarshal(data, response); err != nil {
+			return httpResponse.StatusCode, fmt.Errorf("vault: invalid response from %v: %w", path, err)
+		}
+	}
+	if httpResponse.StatusCode/100 != 2 {
+		return httpResponse.StatusCode, fmt.Errorf("vault: %v %v failed with status %v: %v", method, path, httpResponse.StatusCode, strings.Join(response.Errors, ", "))
+	}
+	switch actual := output.(type) {
+	case nil:
+	case *Response:
+		*actual = *response
+	default:
+		if len(response.Data) > 0 {
+			if err = json.Unmarshal(response.Data, output); err != nil {
+				return httpResponse.StatusCode, fmt.Errorf("vault: invalid response data from %v: %w", path, err)
+			}
+		}
+	}
+	return httpResponse.StatusCode, nil
+}
+
+func (c *Client) isAppRole() bool {
+	return c.secretKey != nil && c.secretKey.Key != "" && c.secretKey.Key != tokenKey
+}
+
+func (c *Client) authToken(ctx context.Context, force bool) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.token != "" && !force {
+		return c.token, nil
+	}
+	if !c.isAppRole() {
+		if c.secretKey != nil {
+			c.token = c.secretKey.Secret
+		}
+		return c.token, nil
+	}
+	login := map[string]string{"role_id": c.secretKey.Key, "secret_id": c.secretKey.Secret}
+	response := &Response{}
+	if _, err := c.do(ctx, http.MethodPost, "auth/"+strings.Trim(c.config.AuthMount, "/")+"/login", "", login, response); err != nil {
+		return "", fmt.Errorf("vault: approle login failed: %w", err)
+	}
+	if response.Auth == nil || response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault: approle login returned empty token")
+	}
+	c.token = response.Auth.ClientToken
+	return c.token, nil
+}
+
+// NewWithSecretKey creates a vault client with token (Secret) or AppRole (Key: role_id, Secret: secret_id) credentials
+func NewWithSecretKey(config *Config, secretKey *cred.SecretKey, opts ...Option) *Client {
+	if config == nil {
+		config = &Config{}
+	}
+	config.Init()
+	ret := &Client{config: config, secretKey: secretKey, httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	return ret
+}
+
+// New creates a vault client, credentials are loaded from config cred.SecretKey resource or VAULT_TOKEN env variable
+func New(ctx context.Context, config *Config, opts ...Option) (*Client, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	secretKey := &cred.SecretKey{Key: tokenKey, Secret: os.Getenv("VAULT_TOKEN")}
+	if config.Credentials != nil {
+		config.Credentials.SetTarget(reflect.TypeOf(cred.SecretKey{}))
+		secret, err := scy.New().Load(ctx, config.Credentials)
+		if err != nil {
+			return nil, fmt.Errorf("vault: failed to load credentials: %w", err)
+		}
+		var ok bool
+		if secretKey, ok = secret.Target.(*cred.SecretKey); !ok {
+			return nil, fmt.Errorf("vault: unsupported credentials type: %T", secret.Target)
+		}
+	}
+	ret := NewWithSecretKey(config, secretKey, opts...)
+	if ret.config.Address == "" {
+		return nil, fmt.Errorf("vault: address was empty")
+	}
+	return ret, nil
+}
@@ -0,0 +1,29 @@
+package vault
+
+import (
+	"github.com/viant/scy"
+	"os"
+)
+
+const defaultAuthMount = "approle"
+
+// Config represents vault client config
+type Config struct {
+	Address     string        `json:",omitempty" yaml:"Address,omitempty"`
+	Namespace   string        `json:",omitempty" yaml:"Namespace,omitempty"`
+	AuthMount   string        `json:",omitempty" yaml:"AuthMount,omitempty"`   //approle auth mount, approle by default
+	Credentials *scy.Resource `json:",omitempty" yaml:"Credentials,omitempty"` //cred.SecretKey resource: token as Secret, or AppRole role_id as Key and secret_id as Secret
+}
+
+// Init initialises config defaults
+func (c *Config) Init() {
+	if c.Address == "" {
+		c.Address = os.Getenv("VAULT_ADDR")
+	}
+	if c.Namespace == "" {
+		c.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if c.AuthMount == "" {
+		c.AuthMount = defaultAuthMount
+	}
+}
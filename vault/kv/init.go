@@ -0,0 +1,7 @@
+package kv
+
+import "github.com/viant/afs"
+
+func init() {
+	afs.GetRegistry().Register(Scheme, Provider)
+}
@@ -0,0 +1,74 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"github.com/viant/afs/base"
+	"github.com/viant/afs/option"
+	"github.com/viant/afs/storage"
+	"github.com/viant/afs/url"
+	"github.com/viant/scy/vault"
+	"sync"
+)
+
+var errUnsupported = fmt.Errorf("unsupported operation")
+
+var defaultClient struct {
+	client *vault.Client
+	mux    sync.RWMutex
+}
+
+// SetClient sets default vault client used when storage options do not supply one
+func SetClient(client *vault.Client) {
+	defaultClient.mux.Lock()
+	defer defaultClient.mux.Unlock()
+	defaultClient.client = client
+}
+
+type manager struct {
+	*base.Manager
+}
+
+// Copy moves data from source to dest
+func (m *manager) Copy(ctx context.Context, sourceURL, destURL string, options ...storage.Option) error {
+	return errUnsupported
+}
+
+// Move moves data from source to dest
+func (m *manager) Move(ctx context.Context, sourceURL, destURL string, options ...storage.Option) error {
+	return errUnsupported
+}
+
+func (m *manager) provider(ctx context.Context, baseURL string, options ...storage.Option) (storage.Storager, error) {
+	options = m.Options(options)
+	var client *vault.Client
+	option.Assign(options, &client)
+	if client == nil {
+		defaultClient.mux.RLock()
+		client = defaultClient.client
+		defaultClient.mux.RUnlock()
+	}
+	if client == nil {
+		var err error
+		if client, err = vault.New(ctx, &vault.Config{}); err != nil {
+			return nil, err
+		}
+	}
+	return newStorager(client, url.Host(baseURL)), nil
+}
+
+func newManager(options ...storage.Option) *manager {
+	result := &manager{}
+	result.Manager = base.New(result, Scheme, result.provider, options)
+	return result
+}
+
+// New creates vault kv manager
+func New(options ...storage.Option) storage.Manager {
+	return newManager(options...)
+}
+
+// Provider returns vault kv manager
+func Provider(options ...storage.Option) (storage.Manager, error) {
+	return New(options...), nil
+}
@@ -0,0 +1,4 @@
+package kv
+
+// Scheme represents vault kv storage scheme, i.e. vault://kv/data/app/db where host is kv mount
+const Scheme = "vault"
@@ -0,0 +1,174 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/viant/afs/file"
+	"github.com/viant/afs/storage"
+	"github.com/viant/scy/vault"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	valueKey      = "value"
+	encodingKey   = "encoding"
+	base64Value   = "base64"
+	versionedPath = "data/"
+)
+
+type storager struct {
+	client *vault.Client
+	mount  string
+}
+
+type secret struct {
+	data     map[string]interface{}
+	modified time.Time
+}
+
+// Exists returns true if location exists
+func (s *storager) Exists(ctx context.Context, location string, options ...storage.Option) (bool, error) {
+	_, err := s.read(ctx, location)
+	if errors.Is(err, vault.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List lists location assets
+func (s *storager) List(ctx context.Context, location string, options ...storage.Option) ([]os.FileInfo, error) {
+	info, err := s.Get(ctx, location, options...)
+	if err != nil {
+		return nil, err
+	}
+	return []os.FileInfo{info}, nil
+}
+
+// Get returns a file info for supplied location
+func (s *storager) Get(ctx context.Context, location string, options ...storage.Option) (os.FileInfo, error) {
+	record, err := s.read(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := decodePayload(record.data)
+	if err != nil {
+		return nil, err
+	}
+	return file.NewInfo(path.Base(location), int64(len(payload)), file.DefaultFileOsMode, record.modified, false), nil
+}
+
+// Open returns a reader closer for supplied resources
+func (s *storager) Open(ctx context.Context, location string, options ...storage.Option) (io.ReadCloser, error) {
+	record, err := s.read(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := decodePayload(record.data)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(payload)), nil
+}
+
+// Upload uploads
+func (s *storager) Upload(ctx context.Context, destination string, mode os.FileMode, reader io.Reader, options ...storage.Option) error {
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	data := encodePayload(payload)
+	var request interface{} = data
+	if isVersioned(destination) {
+		request = map[string]interface{}{"data": data}
+	}
+	return s.client.Do(ctx, http.MethodPost, s.path(destination), request, nil)
+}
+
+// Create create file or directory
+func (s *storager) Create(ctx context.Context, destination string, mode os.FileMode, reader io.Reader, isDir bool, options ...storage.Option) error {
+	if isDir {
+		return nil
+	}
+	return s.Upload(ctx, destination, mode, reader, options...)
+}
+
+// Delete deletes locations
+func (s *storager) Delete(ctx context.Context, location string, options ...storage.Option) error {
+	return s.client.Do(ctx, http.MethodDelete, s.path(location), nil, nil)
+}
+
+// Close closes storage
+func (s *storager) Close() error {
+	return nil
+}
+
+func (s *storager) read(ctx context.Context, location string) (*secret, error) {
+	data := map[string]interface{}{}
+	if !isVersioned(location) {
+		if err := s.client.Do(ctx, http.MethodGet, s.path(location), nil, &data); err != nil {
+			return nil, err
+		}
+		return &secret{data: data}, nil
+	}
+	response := &struct {
+		Data     map[string]interface{} `json:"data"`
+		Metadata struct {
+			CreatedTime time.Time `json:"created_time"`
+		} `json:"metadata"`
+	}{}
+	if err := s.client.Do(ctx, http.MethodGet, s.path(location), nil, response); err != nil {
+		return nil, err
+	}
+	if response.Data == nil {
+		return nil, vault.ErrNotFound
+	}
+	return &secret{data: response.Data, modified: response.Metadata.CreatedTime}, nil
+}
+
+func (s *storager) path(location string) string {
+	return s.mount + "/" + strings.Trim(location, "/")
+}
+
+func isVersioned(location string) bool {
+	return strings.HasPrefix(strings.TrimLeft(location, "/"), versionedPath)
+}
+
+// encodePayload stores JSON object as kv data, other payloads as value (base64 encoded if not valid UTF-8)
+func encodePayload(payload []byte) map[string]interface{} {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		data := map[string]interface{}{}
+		if err := json.Unmarshal(trimmed, &data); err == nil {
+			return data
+		}
+	}
+	if utf8.Valid(payload) {
+		return map[string]interface{}{valueKey: string(payload)}
+	}
+	return map[string]interface{}{valueKey: base64.StdEncoding.EncodeToString(payload), encodingKey: base64Value}
+}
+
+func decodePayload(data map[string]interface{}) ([]byte, error) {
+	value, ok := data[valueKey].(string)
+	encoding, _ := data[encodingKey].(string)
+	if ok && (len(data) == 1 || (len(data) == 2 && encoding != "")) {
+		if encoding == base64Value {
+			return base64.StdEncoding.DecodeString(value)
+		}
+		return []byte(value), nil
+	}
+	return json.Marshal(data)
+}
+
+func newStorager(client *vault.Client, mount string) *storager {
+	return &storager{client: client, mount: mount}
+}
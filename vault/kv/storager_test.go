@@ -0,0 +1,120 @@
+package kv_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/afs/storage"
+	"github.com/viant/scy"
+	"github.com/viant/scy/cred"
+	_ "github.com/viant/scy/kms/blowfish"
+	"github.com/viant/scy/vault"
+	_ "github.com/viant/scy/vault/kv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// kvStandIn emulates vault approle login and kv v1/v2 endpoints
+func kvStandIn(roleID, secretID string) *httptest.Server {
+	var mux sync.Mutex
+	store := map[string]map[string]interface{}{}
+	const token = "s.approle"
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		if path == "auth/approle/login" {
+			login := map[string]string{}
+			_ = json.NewDecoder(r.Body).Decode(&login)
+			if login["role_id"] != roleID || login["secret_id"] != secretID {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid role or secret ID"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"` + token + `"}}`))
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		versioned := strings.HasPrefix(path, "kv/data/")
+		switch r.Method {
+		case http.MethodPost:
+			data := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&data)
+			if versioned {
+				data = data["data"].(map[string]interface{})
+			}
+			store[path] = data
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			data, ok := store[path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			var response interface{} = map[string]interface{}{"data": data}
+			if versioned {
+				response = map[string]interface{}{"data": map[string]interface{}{"data": data, "metadata": map[string]interface{}{"created_time": time.Now().Format(time.RFC3339Nano), "version": 1}}}
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		}
+	}))
+}
+
+func TestStorager(t *testing.T) {
+	server := kvStandIn("role-1", "secret-1")
+	defer server.Close()
+	client := vault.NewWithSecretKey(&vault.Config{Address: server.URL}, &cred.SecretKey{Key: "role-1", Secret: "secret-1"})
+	options := []storage.Option{client}
+
+	var testCases = []struct {
+		description string
+		resource    *scy.Resource
+		secret      *scy.Secret
+		expect      interface{}
+	}{
+		{
+			description: "kv v2 encrypted raw secret",
+			resource:    scy.NewResource("key", "vault://kv/data/app/raw", "blowfish://default"),
+			secret:      scy.NewSecret("this is secret", nil),
+			expect:      "this is secret",
+		},
+		{
+			description: "kv v2 securable secret",
+			resource:    scy.NewResource(cred.Basic{}, "vault://kv/data/app/db", "blowfish://default"),
+			secret:      scy.NewSecret(&cred.Basic{Username: "Bob", Password: "ch@nge!Me"}, nil),
+			expect:      &cred.Basic{Username: "Bob", Password: "ch@nge!Me"},
+		},
+		{
+			description: "kv v1 plain secret",
+			resource:    scy.NewResource("key", "vault://secret/app/token", ""),
+			secret:      scy.NewSecret("plain token", nil),
+			expect:      "plain token",
+		},
+	}
+	for _, testCase := range testCases {
+		srv := scy.New()
+		ctx := context.Background()
+		testCase.resource.Options = options
+		testCase.secret.Resource = testCase.resource
+		if !assert.Nil(t, srv.Store(ctx, testCase.secret), testCase.description) {
+			continue
+		}
+		secret, err := srv.Load(ctx, testCase.resource)
+		if !assert.Nil(t, err, testCase.description) {
+			continue
+		}
+		assert.EqualValues(t, testCase.expect, secret.Target, testCase.description)
+	}
+
+	_, err := scy.New().Load(context.Background(), &scy.Resource{URL: "vault://kv/data/app/missing", Options: options, MaxRetry: 1})
+	assert.NotNil(t, err, "missing secret")
+}
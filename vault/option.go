@@ -0,0 +1,13 @@
+package vault
+
+import "net/http"
+
+// Option represents a client option
+type Option func(c *Client)
+
+// WithHTTPClient sets http client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
@@ -0,0 +1,160 @@
+package scy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/viant/afs/file"
+	"github.com/viant/afs/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerationSuffix separates resource URL and generation number of generation-suffixed objects, i.e. db.json.v3
+const GenerationSuffix = ".v"
+
+// Version represents secret version, native backend version or generation number
+type Version struct {
+	ID       string
+	Modified time.Time
+	Size     int64
+}
+
+// Versions returns resource versions sorted from the oldest, natively versioned backends (gcp secretmanager) list
+// backend versions, other backends list generation-suffixed objects written when Resource.Generations is set
+func (s *Service) Versions(ctx context.Context, resource *Resource) ([]*Version, error) {
+	if err := resource.Validate(); err != nil {
+		return nil, err
+	}
+	URL := expandHome(resource.URL)
+	native := isNativelyVersioned(URL)
+	listURL, prefix := URL, ""
+	if native {
+		listURL = unversionedURL(URL)
+	} else {
+		var name string
+		listURL, name = url.Split(URL, file.Scheme)
+		prefix = name + GenerationSuffix
+	}
+	objects, err := s.fs.List(ctx, listURL, resource.Options...)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Version
+	for _, object := range objects {
+		name := object.Name()
+		if native {
+			name = path.Base(name)
+		} else if !strings.HasPrefix(name, prefix) || object.IsDir() {
+			continue
+		}
+		ID := strings.TrimPrefix(name, prefix)
+		if _, err := strconv.ParseUint(ID, 10, 64); err != nil {
+			continue
+		}
+		result = append(result, &Version{ID: ID, Modified: object.ModTime(), Size: object.Size()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		left, _ := strconv.ParseUint(result[i].ID, 10, 64)
+		right, _ := strconv.ParseUint(result[j].ID, 10, 64)
+		return left < right
+	})
+	return result, nil
+}
+
+// LoadVersion loads resource secret version
+func (s *Service) LoadVersion(ctx context.Context, resource *Resource, version string) (*Secret, error) {
+	if version == "" {
+		return nil, fmt.Errorf("version was empty")
+	}
+	clone := *resource
+	clone.Version = version
+	return s.Load(ctx, &clone)
+}
+
+// Rollback makes resource version current, natively versioned backends get a new version with the version payload,
+// ciphertext is copied as is, so the version key is still required to load it
+func (s *Service) Rollback(ctx context.Context, resource *Resource, version string) error {
+	if err := resource.Validate(); err != nil {
+		return err
+	}
+	if version == "" {
+		return fmt.Errorf("version was empty")
+	}
+	clone := *resource
+	clone.Version = version
+	data, err := s.download(ctx, &clone, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download version %v: %w", version, err)
+	}
+	current := *resource
+	current.Version = ""
+	current.URL = clone.URL
+	defer s.InvalidateCache(current.URL)
+	if err = s.fs.Upload(ctx, current.URL, file.DefaultFileOsMode, bytes.NewReader(data), current.Options...); err != nil {
+		return err
+	}
+	return s.recordGeneration(ctx, &current)
+}
+
+// recordGeneration copies current object to the next generation-suffixed object and removes generations above Resource.Generations
+func (s *Service) recordGeneration(ctx context.Context, resource *Resource) error {
+	URL := expandHome(resource.URL)
+	if resource.Generations <= 0 || isNativelyVersioned(URL) || len(resource.Data) > 0 {
+		return nil
+	}
+	versions, err := s.Versions(ctx, resource)
+	if err != nil {
+		return err
+	}
+	next := uint64(1)
+	if len(versions) > 0 {
+		last, _ := strconv.ParseUint(versions[len(versions)-1].ID, 10, 64)
+		next = last + 1
+	}
+	if err = s.fs.Copy(ctx, URL, versionURL(URL, strconv.FormatUint(next, 10)), resource.Options...); err != nil {
+		return fmt.Errorf("failed to record generation %v: %w", next, err)
+	}
+	for i := 0; i < len(versions)+1-resource.Generations; i++ {
+		if err = s.fs.Delete(ctx, versionURL(URL, versions[i].ID), resource.Options...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNativelyVersioned returns true for backends with native versions (gcp secretmanager)
+func isNativelyVersioned(URL string) bool {
+	return url.Scheme(URL, file.Scheme) == "gcp" && url.Host(URL) == "secretmanager"
+}
+
+// versionURL returns URL of resource version
+func versionURL(URL, version string) string {
+	if isNativelyVersioned(URL) {
+		return unversionedURL(URL) + "/versions/" + version
+	}
+	return URL + GenerationSuffix + version
+}
+
+// unversionedURL returns natively versioned URL without version, i.e. gcp://secretmanager/projects/p/secrets/name
+func unversionedURL(URL string) string {
+	if index := strings.Index(URL, "/versions/"); index != -1 {
+		return URL[:index]
+	}
+	return URL
+}
+
+// isGeneration returns true if name is a generation-suffixed object of any of names
+func isGeneration(name string, names map[string]bool) bool {
+	index := strings.LastIndex(name, GenerationSuffix)
+	if index == -1 {
+		return false
+	}
+	if _, err := strconv.ParseUint(name[index+len(GenerationSuffix):], 10, 64); err != nil {
+		return false
+	}
+	return names[name[:index]]
+}
@@ -0,0 +1,72 @@
+package scy_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/kms/memory"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestService_Versions(t *testing.T) {
+	ctx := context.Background()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("versions")
+	baseURL := t.TempDir()
+	URL := path.Join(baseURL, "db.sec")
+	srv := scy.New(scy.WithCache(scy.CacheConfig{}))
+	newResource := func() *scy.Resource {
+		resource := scy.NewResource("", URL, key)
+		resource.Generations = 2
+		return resource
+	}
+	for _, value := range []string{"v1", "v2", "v3"} {
+		assert.Nil(t, srv.Store(ctx, scy.NewSecret(value, newResource())))
+	}
+
+	versions, err := srv.Versions(ctx, newResource())
+	if !assert.Nil(t, err) {
+		return
+	}
+	var IDs []string
+	for _, version := range versions {
+		IDs = append(IDs, version.ID)
+	}
+	assert.EqualValues(t, []string{"2", "3"}, IDs, "older generations are pruned")
+	_, err = os.Stat(URL + scy.GenerationSuffix + "1")
+	assert.True(t, os.IsNotExist(err), "pruned generation")
+
+	secret, err := srv.LoadVersion(ctx, newResource(), "2")
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "v2", secret.String())
+	}
+	pinned := newResource()
+	pinned.Version = "2"
+	assert.NotNil(t, srv.Store(ctx, scy.NewSecret("v4", pinned)), "pinned version is read only")
+	_, err = srv.LoadVersion(ctx, newResource(), "1")
+	assert.NotNil(t, err, "pruned version")
+
+	secret, err = srv.Load(ctx, newResource())
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "v3", secret.String(), "cached current version")
+	}
+	if !assert.Nil(t, srv.Rollback(ctx, newResource(), "2")) {
+		return
+	}
+	secret, err = srv.Load(ctx, newResource())
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "v2", secret.String(), "rolled back version")
+	}
+	versions, _ = srv.Versions(ctx, newResource())
+	if assert.Len(t, versions, 2) {
+		assert.EqualValues(t, "4", versions[1].ID, "rollback records generation")
+	}
+
+	report, err := srv.RekeyAll(ctx, scy.NewResource("", baseURL, key), cipher.Key("rotated"), false)
+	if assert.Nil(t, err) {
+		assert.Len(t, report.Rekeyed, 1, "generations are not rekeyed")
+		assert.Len(t, report.Failed, 0)
+	}
+}
@@ -0,0 +1,168 @@
+package scy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+const (
+	//DefaultWatchInterval represents default secret polling interval
+	DefaultWatchInterval = 30 * time.Second
+	//DefaultWatchDebounce represents default period a changed secret has to stay unchanged before it is delivered
+	DefaultWatchDebounce = time.Second
+)
+
+// WatchOption represents Watch option
+type WatchOption func(w *watcher)
+
+// WithWatchInterval sets polling interval
+func WithWatchInterval(interval time.Duration) WatchOption {
+	return func(w *watcher) {
+		w.interval = interval
+	}
+}
+
+// WithWatchDebounce sets period a changed secret has to stay unchanged before it is delivered, zero delivers immediately
+func WithWatchDebounce(debounce time.Duration) WatchOption {
+	return func(w *watcher) {
+		w.debounce = debounce
+	}
+}
+
+// WithWatchErrorHandler sets handler of polling, download and decryption errors, watching continues after an error
+func WithWatchErrorHandler(handler func(err error)) WatchOption {
+	return func(w *watcher) {
+		w.onError = handler
+	}
+}
+
+type watcher struct {
+	service  *Service
+	resource *Resource
+	interval time.Duration
+	debounce time.Duration
+	onChange func(secret *Secret)
+	onError  func(err error)
+	modified time.Time
+	size     int64
+	hash     []byte
+}
+
+// Watch loads resource secret, delivers it to onChange and polls resource for changes until ctx is done,
+// an object is re-read when its modification time or size changes (every poll when backend does not report them),
+// a secret is delivered only when its content hash changes, the initial load error is returned
+func (s *Service) Watch(ctx context.Context, resource *Resource, onChange func(secret *Secret), options ...WatchOption) error {
+	if err := resource.Validate(); err != nil {
+		return err
+	}
+	if onChange == nil {
+		return fmt.Errorf("watch onChange was empty")
+	}
+	clone := *resource
+	w := &watcher{service: s, resource: &clone, interval: DefaultWatchInterval, debounce: DefaultWatchDebounce, onChange: onChange, onError: func(err error) {}}
+	for _, option := range options {
+		option(w)
+	}
+	if w.interval <= 0 {
+		return fmt.Errorf("invalid watch interval: %v", w.interval)
+	}
+	modified, size, _ := w.stat(ctx)
+	data, err := s.download(ctx, w.resource, w.resource.Data)
+	if err != nil {
+		return err
+	}
+	secret, err := w.decode(ctx, data)
+	if err != nil {
+		return err
+	}
+	w.modified, w.size = modified, size
+	w.onChange(secret)
+	go w.run(ctx)
+	return nil
+}
+
+func (w *watcher) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			w.onError(err)
+		}
+	}
+}
+
+// poll delivers secret if its content changed and settled for debounce period, object modification time and size are
+// recorded only once its content was decoded (or was unchanged), so a failed download or decode is retried on the next poll
+func (w *watcher) poll(ctx context.Context) error {
+	modified, size, changed := w.stat(ctx)
+	if !changed {
+		return nil
+	}
+	data, err := w.service.download(ctx, w.resource, w.resource.Data)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+	for w.debounce > 0 && !bytes.Equal(hash[:], w.hash) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.debounce):
+		}
+		modified, size, _ = w.stat(ctx)
+		settled, err := w.service.download(ctx, w.resource, w.resource.Data)
+		if err != nil {
+			return err
+		}
+		settledHash := sha256.Sum256(settled)
+		if settledHash == hash {
+			break
+		}
+		data, hash = settled, settledHash
+	}
+	if bytes.Equal(hash[:], w.hash) {
+		w.modified, w.size = modified, size
+		return nil
+	}
+	secret, err := w.decode(ctx, data)
+	if err != nil {
+		return err
+	}
+	w.modified, w.size = modified, size
+	w.service.InvalidateCache(w.resource.URL)
+	w.onChange(secret)
+	return nil
+}
+
+// stat returns object modification time and size, changed is true if they differ from the recorded ones or can not be determined
+func (w *watcher) stat(ctx context.Context) (time.Time, int64, bool) {
+	if len(w.resource.Data) > 0 {
+		return time.Time{}, 0, false
+	}
+	object, err := w.service.fs.Object(ctx, expandHome(w.resource.URL), w.resource.Options...)
+	if err != nil || object.ModTime().IsZero() {
+		return time.Time{}, 0, true
+	}
+	changed := !object.ModTime().Equal(w.modified) || object.Size() != w.size
+	return object.ModTime(), object.Size(), changed
+}
+
+// decode decrypts data and records its hash, hash is recorded only for successfully decoded data so a fix is delivered
+func (w *watcher) decode(ctx context.Context, data []byte) (*Secret, error) {
+	clone := *w.resource
+	secret, err := w.service.load(ctx, &clone, data)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	w.hash = hash[:]
+	return secret, nil
+}
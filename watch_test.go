@@ -0,0 +1,136 @@
+package scy_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/scy"
+	"github.com/viant/scy/kms/memory"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+type watchRecorder struct {
+	mux     sync.Mutex
+	values  []string
+	errors  []error
+	changed chan string
+}
+
+func (r *watchRecorder) onChange(secret *scy.Secret) {
+	r.mux.Lock()
+	r.values = append(r.values, secret.String())
+	r.mux.Unlock()
+	r.changed <- secret.String()
+}
+
+func (r *watchRecorder) onError(err error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.errors = append(r.errors, err)
+}
+
+func (r *watchRecorder) next(t *testing.T) string {
+	select {
+	case value := <-r.changed:
+		return value
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch change was not delivered")
+	}
+	return ""
+}
+
+func TestService_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("watch")
+	URL := path.Join(t.TempDir(), "watched.sec")
+	srv := scy.New()
+	store := func(value string) {
+		assert.Nil(t, srv.Store(ctx, scy.NewSecret(value, scy.NewResource("", URL, key))))
+	}
+	store("v1")
+	recorder := &watchRecorder{changed: make(chan string, 10)}
+	err := srv.Watch(ctx, scy.NewResource("", URL, key), recorder.onChange,
+		scy.WithWatchInterval(5*time.Millisecond), scy.WithWatchDebounce(0), scy.WithWatchErrorHandler(recorder.onError))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, "v1", recorder.next(t), "initial secret")
+	store("v2")
+	assert.EqualValues(t, "v2", recorder.next(t), "rotated secret")
+
+	data, _ := os.ReadFile(URL)
+	assert.Nil(t, os.WriteFile(URL, []byte("corrupted"), 0600))
+	assert.Eventually(t, func() bool {
+		recorder.mux.Lock()
+		defer recorder.mux.Unlock()
+		return len(recorder.errors) > 0
+	}, 2*time.Second, 5*time.Millisecond, "error callback")
+	assert.Nil(t, os.WriteFile(URL, data, 0600))
+	store("v3")
+	assert.EqualValues(t, "v3", recorder.next(t), "recovered")
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	store("v4")
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case value := <-recorder.changed:
+		t.Errorf("unexpected change after cancel: %v", value)
+	default:
+	}
+	recorder.mux.Lock()
+	assert.EqualValues(t, []string{"v1", "v2", "v3"}, recorder.values, "unchanged content is not delivered")
+	recorder.mux.Unlock()
+}
+
+func TestService_Watch_Recover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cipher := memory.RegisterCleanup(t)
+	key := cipher.Key("recover")
+	URL := path.Join(t.TempDir(), "recovered.sec")
+	srv := scy.New()
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret("v1", scy.NewResource("", URL, key))))
+	recorder := &watchRecorder{changed: make(chan string, 10)}
+	err := srv.Watch(ctx, scy.NewResource("", URL, key), recorder.onChange,
+		scy.WithWatchInterval(5*time.Millisecond), scy.WithWatchDebounce(0), scy.WithWatchErrorHandler(recorder.onError))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, "v1", recorder.next(t), "initial secret")
+
+	cipher.Fail(key, fmt.Errorf("kms unavailable"), memory.Decrypt)
+	assert.Nil(t, srv.Store(ctx, scy.NewSecret("v2", scy.NewResource("", URL, key))))
+	assert.Eventually(t, func() bool {
+		recorder.mux.Lock()
+		defer recorder.mux.Unlock()
+		return len(recorder.errors) > 0
+	}, 2*time.Second, 5*time.Millisecond, "transient decrypt error")
+	cipher.Fail(key, nil)
+	assert.EqualValues(t, "v2", recorder.next(t), "unchanged object is re-read after a failed decode")
+}
+
+func TestService_Watch_Debounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	URL := path.Join(t.TempDir(), "debounced.txt")
+	assert.Nil(t, os.WriteFile(URL, []byte("v1"), 0600))
+	recorder := &watchRecorder{changed: make(chan string, 10)}
+	srv := scy.New()
+	if !assert.Nil(t, srv.Watch(ctx, scy.NewResource("", URL, ""), recorder.onChange, scy.WithWatchInterval(5*time.Millisecond), scy.WithWatchDebounce(100*time.Millisecond))) {
+		return
+	}
+	assert.EqualValues(t, "v1", recorder.next(t))
+	assert.Nil(t, os.WriteFile(URL, []byte("v2"), 0600))
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(t, os.WriteFile(URL, []byte("v3-final"), 0600))
+	assert.EqualValues(t, "v3-final", recorder.next(t), "intermediate change is debounced")
+
+	assert.NotNil(t, srv.Watch(ctx, scy.NewResource("", URL+".missing", ""), recorder.onChange), "initial load error")
+}